@@ -43,18 +43,82 @@ function fromPre(elm) {
     terminal.loadAddon(fitAddon);
     fitAddon.fit();
 
+    window.addEventListener("resize", () => fitAddon.fit());
+
     terminal.write(elm.innerText);
 
     elm.terminal = terminal;
 }
 
 async function stream(terminal, url) {
-    const response = await fetch(url);
-    
+    // Let the program render for the size of the terminal
+    url = new URL(url, window.location.href);
+    url.searchParams.set("rows", terminal.rows);
+    url.searchParams.set("cols", terminal.cols);
+    url.searchParams.set("term", "xterm-256color");
+
+    // Resizes are sent as "CSI 8 ; rows ; cols t" sequences in a streamed request body.
+    // Browsers stream request bodies only via HTTP/2 or newer, otherwise the terminal is not resized.
+    let onResize, close;
+    let options = {};
+    if (supportsRequestStreams) {
+        const body = new ReadableStream({
+            start(controller) {
+                const encoder = new TextEncoder();
+
+                onResize = terminal.onResize(({ rows, cols }) => {
+                    controller.enqueue(encoder.encode(`\x1b[8;${rows};${cols}t`));
+                });
+
+                close = () => {
+                    onResize.dispose();
+                    controller.close();
+                };
+            },
+            cancel() {
+                onResize.dispose();
+            },
+        });
+
+        options = { method: "POST", body, duplex: "half" };
+    }
+
+    let response;
+    try {
+        response = await fetch(url, options);
+    } catch {
+        onResize?.dispose();
+        close = undefined;
+
+        response = await fetch(url);
+    }
+
     terminal.reset();
     for await (const chunk of response.body) {
         terminal.write(chunk);
     }
+
+    try {
+        close?.();
+    } catch {
+        // The request body has already been cancelled
+    }
 }
 
+// See: https://developer.chrome.com/docs/capabilities/web-apis/fetch-streaming-requests#feature_detection
+const supportsRequestStreams = (() => {
+    let duplexAccessed = false;
+
+    const hasContentType = new Request('', {
+        body: new ReadableStream(),
+        method: 'POST',
+        get duplex() {
+            duplexAccessed = true;
+            return 'half';
+        },
+    }).headers.has('Content-Type');
+
+    return duplexAccessed && !hasContentType;
+})();
+
 export { Terminal, create, stream, fromPre };
\ No newline at end of file
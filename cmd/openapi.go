@@ -0,0 +1,34 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/stv0g/nixpresso/pkg/util"
+)
+
+var openAPICmd = &cobra.Command{
+	Use:   "openapi [flags] <handler> -- [nix-flags]",
+	Short: "Print the OpenAPI document of a handler",
+	Args:  cobra.RangeArgs(0, 1),
+	RunE:  runOpenAPI,
+}
+
+func init() {
+	rootCmd.AddCommand(openAPICmd)
+}
+
+func runOpenAPI(cmd *cobra.Command, args []string) error {
+	h, err := newHandler(args)
+	if err != nil {
+		return err
+	}
+	defer h.Close() //nolint:errcheck
+
+	util.DumpJSONf(os.Stdout, h.OpenAPI())
+
+	return nil
+}
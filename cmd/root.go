@@ -13,8 +13,10 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/stv0g/nixpresso/pkg"
 	"github.com/stv0g/nixpresso/pkg/handler"
+	"github.com/stv0g/nixpresso/pkg/limits"
 	"github.com/stv0g/nixpresso/pkg/nix"
 	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/seccomp"
 	"github.com/stv0g/nixpresso/pkg/util"
 )
 
@@ -53,13 +55,32 @@ func init() {
 	pf.DurationVar(&opts.MaxEvalTime, "max-eval-time", 5*time.Minute, "maximum duration for the evaluation phase. A zero or negative value means there will be no timeout")
 	pf.DurationVar(&opts.MaxBuildTime, "max-build-time", 10*time.Minute, "maximum duration for the build phase. A zero or negative value means there will be no timeout")
 	pf.DurationVar(&opts.MaxRunTime, "max-run-time", 10*time.Minute, "maximum duration for the run phase. A zero or negative value means there will be no timeout")
+	pf.DurationVar(&opts.ProxyIdleTimeout, "proxy-idle-timeout", 10*time.Minute, "duration after which idle services started by the proxy mode or for FastCGI and SCGI applications are stopped. A zero or negative value means they keep running")
+	pf.Int64Var(&opts.Limits.AddressSpace, "limit-address-space", 0, "maximum size of the virtual memory of executed programs and Nix in bytes. A zero value means there will be no limit")
+	pf.DurationVar(&opts.Limits.CPUTime, "limit-cpu-time", 0, "maximum CPU time of executed programs and Nix. A zero value means there will be no limit")
+	pf.Int64Var(&opts.Limits.OpenFiles, "limit-open-files", 0, "maximum number of open files of executed programs and Nix. A zero value means there will be no limit")
+	pf.Int64Var(&opts.Limits.Processes, "limit-processes", 0, "maximum number of processes of executed programs and Nix. Requires --cgroup. A zero value means there will be no limit")
+	pf.Int64Var(&opts.Limits.FileSize, "limit-file-size", 0, "maximum size of files written by executed programs and Nix in bytes. A zero value means there will be no limit")
+	pf.Int64Var(&opts.Limits.Memory, "limit-memory", 0, "maximum memory of executed programs and Nix in bytes. Requires --cgroup. A zero value means there will be no limit")
+	pf.Float64Var(&opts.Limits.CPU, "limit-cpu", 0, "maximum number of CPUs used by executed programs and Nix. Requires --cgroup. A zero value means there will be no limit")
+	pf.StringVar(&opts.Cgroup, "cgroup", "", "path of a delegated cgroup v2 in which a cgroup is created for each executed program and Nix to enforce memory, CPU and process limits")
+	pf.StringVar(&opts.SeccompProfiles, "seccomp-profiles", "", "JSON file with named seccomp profiles which handlers can select for programs of the run mode")
+	pf.StringVar(&opts.SeccompProfile, "seccomp-profile", seccomp.GlobalProfileName(), "name of the seccomp profile which is applied to all programs of the run mode. The built-in 'default' profile denies system calls like ptrace, mount, kexec_load and bpf. The value 'none' disables it")
 	pf.Int64Var(&opts.MaxRequestBytes, "max-request-bytes", 32<<20, "maximum number of bytes the server will read from the request body")
 	pf.Int64Var(&opts.MaxResponseBytes, "max-response-bytes", 32<<20, "maximum number of bytes the server will serve in the response body")
+	pf.Int64Var(&opts.CompressMinBytes, "compress-min-bytes", 1<<10, "minimum size of compressible responses which are compressed on-the-fly. A negative value disables on-the-fly compression")
 	pf.BoolVarP(&opts.AllowStore, "allow-store", "s", true, "allow serving or executing content from Nix store")
 	pf.VarP(&opts.AllowedModes, "allow-mode", "m", fmt.Sprintf("allowed response modes (default %s)", strings.Join(options.DefaultModes, ", ")))
 	pf.VarP(&opts.AllowedTypes, "allow-type", "t", fmt.Sprintf("alowed response types (default %s)", strings.Join(options.AllTypes, ", ")))
 	pf.VarP(&opts.AllowedPaths, "allow-path", "p", "allowed paths from which content can be served or executed")
+	pf.BoolVar(&opts.Sandbox, "sandbox", false, "run all programs of the run mode in a sandbox of unprivileged Linux namespaces")
+	pf.StringSliceVar(&opts.AllowedCapabilities, "allow-capability", nil, "sandbox capabilities which handlers are allowed to request. Either 'network' or Linux capabilities like 'CAP_NET_BIND_SERVICE'")
+	pf.Var(&opts.RunUsers, "run-users", "range of UIDs and GIDs like '100000-100999' from which a dedicated user with a private home directory is allocated for each program of the run mode except persistent FastCGI and SCGI applications. Requires CAP_SETUID, CAP_SETGID, CAP_CHOWN, CAP_DAC_OVERRIDE and CAP_KILL")
+	pf.StringVar(&opts.SecretsDir, "secrets-dir", "", "directory with secrets which handlers can inject into programs of the run mode by name, e.g. the credentials directory of systemd's LoadCredential=")
 	pf.StringVarP(&opts.BasePath, "base-path", "b", "", "initial base path to pass to the handler")
+	pf.StringVar(&opts.CacheSecretKeyFile, "cache-secret-key", "", "secret key file used to sign narinfo files served in the cache mode")
+	pf.StringVar(&opts.DotPath, "dot", "", "path to the GraphViz 'dot' binary used to render closure graphs as SVG. An empty value disables SVG rendering")
+	pf.StringVar(&opts.OpenAPIPath, "openapi-path", "", "path at which the OpenAPI document of the handler is served. An empty value disables it")
 	pf.BoolVarP(&debug, "debug", "d", false, "enable debug logging")
 	pf.BoolVarP(&opts.EvalCache, "eval-cache", "c", true, "enable evaluation caching")
 	pf.BoolVarP(&inspect, "inspect", "i", false, "inspect handler and print result to standard output")
@@ -85,6 +106,31 @@ func Execute() {
 }
 
 func run(cmd *cobra.Command, args []string) error {
+	h, err := newHandler(args)
+	if err != nil {
+		return err
+	}
+	defer h.Close() //nolint:errcheck
+
+	switch {
+	case inspect:
+		util.DumpJSONf(os.Stdout, h.InspectResult)
+
+	case test != "":
+		if err := h.Test(test, testOverwrite); err != nil {
+			return err
+		}
+
+	default:
+		if err := h.ListenAndServe(addr, maxReadTime, maxWriteTime, tlsCertFilename, tlsKeyFilename); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newHandler(args []string) (*handler.Handler, error) {
 	if len(args) > 0 {
 		opts.Handler = args[0]
 	}
@@ -113,25 +159,16 @@ func run(cmd *cobra.Command, args []string) error {
 		util.DumpJSON(opts)
 	}
 
-	h, err := handler.NewHandler(opts)
-	if err != nil {
-		return fmt.Errorf("failed to create handler: %w", err)
-	}
+	// Children of a non-root service must not inherit the capabilities which it needs itself.
+	opts.Limits.DropCapabilities = limits.HasAmbientCapabilities()
 
-	switch {
-	case inspect:
-		util.DumpJSONf(os.Stdout, h.InspectResult)
+	nix.Limits = &opts.Limits
+	nix.Cgroup = opts.Cgroup
 
-	case test != "":
-		if err := h.Test(test, testOverwrite); err != nil {
-			return err
-		}
-
-	default:
-		if err := h.ListenAndServe(addr, maxReadTime, maxWriteTime, tlsCertFilename, tlsKeyFilename); err != nil {
-			return err
-		}
+	h, err := handler.NewHandler(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create handler: %w", err)
 	}
 
-	return nil
+	return h, nil
 }
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var routesCmd = &cobra.Command{
+	Use:   "routes [flags] <handler> -- [nix-flags]",
+	Short: "Print the route table of a handler",
+	Args:  cobra.RangeArgs(0, 1),
+	RunE:  runRoutes,
+}
+
+func init() {
+	rootCmd.AddCommand(routesCmd)
+}
+
+func runRoutes(cmd *cobra.Command, args []string) error {
+	h, err := newHandler(args)
+	if err != nil {
+		return err
+	}
+	defer h.Close() //nolint:errcheck
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "METHODS\tPATH\tMATCH\tARGS\tDESCRIPTION") //nolint:errcheck
+
+	for _, route := range h.Routes() {
+		methods := "*"
+		if len(route.Methods) > 0 {
+			methods = strings.Join(route.Methods, ",")
+		}
+
+		args := []string{}
+		for _, name := range slices.Sorted(maps.Keys(route.ExpectedArgs)) {
+			if route.ExpectedArgs[name] {
+				name += "?"
+			}
+
+			args = append(args, name)
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", methods, route.Path, route.Match, strings.Join(args, ","), route.Description) //nolint:errcheck
+	}
+
+	return tw.Flush()
+}
@@ -12,7 +12,7 @@ buildGo125Module rec {
   version = "0.1.0";
 
   src = ./.;
-  vendorHash = "sha256-7q7inID7+Xmf47ZILyJ/HyRcjfKjHWxXytALml1Z+8A=";
+  vendorHash = "sha256-RI1VeK4TKnA4tPl2zgQuaU9z9uz94fqjkoB9uj7/ngU=";
 
   ldflags = [
     "-X 'github.com/stv0g/nixpresso/pkg.Version=${version}'"
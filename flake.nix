@@ -69,6 +69,13 @@
           checks = {
             default = self.checks.${system}.nixpresso;
             nixpresso = pkgs.callPackage ./check.nix { inherit self; };
+
+            lib = pkgs.writeText "nixpresso-lib-tests" (
+              import ./lib/tests.nix {
+                inherit (pkgs) lib;
+                inherit (self.lib) url response;
+              }
+            );
           };
 
           formatter = pkgs.nixfmt-rfc-style;
@@ -9,8 +9,11 @@ require (
 	al.essio.dev/pkg/shellescape v1.6.0
 	github.com/creack/pty v1.1.24
 	github.com/elastic/go-freelru v0.16.0
+	github.com/fxamacker/cbor/v2 v2.9.0
+	github.com/klauspost/compress v1.18.0
 	github.com/sergi/go-diff v1.4.0
 	github.com/spf13/cobra v1.10.1
+	go.yaml.in/yaml/v3 v3.0.4
 	golang.org/x/sys v0.36.0
 )
 
@@ -18,4 +21,5 @@ require (
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.10 // indirect
 	github.com/stretchr/testify v1.11.1 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 )
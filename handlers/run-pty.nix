@@ -9,14 +9,9 @@ let
   inherit (nixpresso.lib) handlers mkHandler;
 in
 mkHandler { description = "Run fastfetch in a pseudo-terminal (pty)"; } (
-  { path, meta, ... }:
+  { path, meta, ... }@request:
   if path == "/run" then
-    {
-      body = fastfetch;
-      subPath = "bin/fastfetch";
-      mode = "run";
-      pty = true;
-    }
+    handlers.runInTerminal { drv = fastfetch; } request
   else
     handlers.html {
       title = meta.description;
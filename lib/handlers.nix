@@ -7,7 +7,13 @@
   trivial,
 }:
 let
-  inherit (builtins) readDir readFileType toString;
+  inherit (builtins)
+    head
+    readDir
+    readFileType
+    toJSON
+    toString
+    ;
   inherit (lib)
     concatStrings
     concatStringsSep
@@ -25,13 +31,14 @@ let
     removePrefix
     removeSuffix
     reverseList
+    toInt
     ;
   inherit (trivial) updateMeta toFunctor;
 
   _status = status;
 
-  ifPred =
-    pred: matchHandler: fallbackHandler:
+  ifPredRoute =
+    route: pred: matchHandler: fallbackHandler:
     let
       matchHandlerFct = toFunctor matchHandler;
       fallbackHandlerFct = toFunctor fallbackHandler;
@@ -45,6 +52,24 @@ let
 
       newArgs = matchHandlerArgs // fallbackHandlerArgs // predArgs;
 
+      # Routes of the match handler are nested below the route of this predicate (if known)
+      matchRoutes =
+        if route == null then
+          matchHandlerMeta.routes or [ ]
+        else
+          [
+            (
+              route
+              // {
+                description = matchHandlerMeta.description or null;
+                methods = matchHandlerMeta.methods or null;
+                expectedArgs = matchHandlerArgs;
+                schema = matchHandlerMeta.schema or null;
+                routes = matchHandlerMeta.routes or [ ];
+              }
+            )
+          ];
+
       handler =
         request:
         let
@@ -59,14 +84,19 @@ let
     {
       __functor = _: handler;
       __functionArgs = newArgs;
-      meta = updateMeta matchHandlerMeta fallbackHandlerMeta;
+      # Schemas only apply to the handler which declares them and are not inherited by routers
+      meta = removeAttrs (updateMeta matchHandlerMeta fallbackHandlerMeta) [ "schema" ] // {
+        routes = matchRoutes ++ fallbackHandlerMeta.routes or [ ];
+      };
     };
 
+  ifPred = ifPredRoute null;
+
   ifPred' = pred: ifPred (mirrorFunctionArgs pred (request: if pred request then { } else null));
 
-  ifPath =
-    pred:
-    ifPred (
+  ifPathRoute =
+    route: pred:
+    ifPredRoute route (
       { path, basePath, ... }:
       let
         newPath = pred path;
@@ -80,20 +110,36 @@ let
         }
     );
 
+  ifPath = ifPathRoute null;
+
   ifPathMatch =
     regex:
-    ifPred (
-      { path, ... }@request:
-      let
-        matches = match regex path;
-      in
-      if matches == null then null else request // { inherit matches; }
-    );
+    ifPredRoute
+      {
+        path = regex;
+        match = "regex";
+      }
+      (
+        { path, ... }@request:
+        let
+          matches = match regex path;
+        in
+        if matches == null then null else request // { inherit matches; }
+      );
 
-  ifPathEquals = path: ifPath (p: if path == p then "" else null);
+  ifPathEquals =
+    path:
+    ifPathRoute {
+      inherit path;
+      match = "equals";
+    } (p: if path == p then "" else null);
 
   ifPathHasPrefix =
-    prefix: ifPath (path: if hasPrefix prefix path then removePrefix prefix path else null);
+    prefix:
+    ifPathRoute {
+      path = prefix;
+      match = "prefix";
+    } (path: if hasPrefix prefix path then removePrefix prefix path else null);
 
   /**
     A simple router that matches the first route that matches the request path.
@@ -148,9 +194,13 @@ let
 
   /**
     Serve a files and/or directories with directory indices.
+    Directories are rendered natively by the listing mode instead if `listing` is set.
   */
   servePath =
-    { fsPath }:
+    {
+      fsPath,
+      listing ? false,
+    }:
     assert lib.assertMsg (
       isPath fsPath || isDerivation fsPath || hasPrefix "/nix/store/" fsPath
     ) "fsPath must be a path, a derivation or a store path as a string";
@@ -173,6 +223,13 @@ let
           mode = "serve";
           type = "path";
         }
+      else if fileType == "directory" && listing then
+        {
+          body = fsPath;
+          inherit subPath;
+          mode = "listing";
+          type = "path";
+        }
       else if fileType == "directory" then
         directoryIndex { fsPath = rfsPath; } { inherit path; }
       else
@@ -182,6 +239,152 @@ let
         }
     );
 
+  /**
+    List the contents of a directory in a derivation or store path.
+    The listing is rendered natively as JSON or HTML depending on the `Accept` header
+    and can be sorted and paginated with the `sort`, `order`, `page` and `limit` query parameters.
+  */
+  directoryListing =
+    { fsPath }:
+    { path, ... }:
+    {
+      body = fsPath;
+      subPath = path;
+      mode = "listing";
+    };
+
+  /**
+    Build all outputs of a derivation and serve the one selected by the `output` query parameter.
+    A JSON manifest of all output paths is returned if no output is selected.
+  */
+  serveOutputs =
+    { drv }:
+    {
+      query,
+      result ? null,
+      ...
+    }:
+    let
+      output = head (query.output or [ null ]);
+    in
+    if result == null then
+      {
+        body = drv;
+        outputs = [ "*" ];
+      }
+    else if output == null then
+      {
+        body = toJSON result.outputPaths;
+        headers."Content-Type" = "application/json";
+      }
+    else if result.outputPaths ? ${output} then
+      {
+        body = result.outputPaths.${output};
+        type = "path";
+      }
+    else
+      htmlError {
+        status = status.notFound;
+        details = "The derivation has no output <tt>${output}</tt>";
+      };
+
+  /**
+    Serve the runtime closure of a derivation or store path as JSON, DOT or SVG graph.
+    The `whyDepends` query parameter restricts the graph to the paths through which it depends on another store path.
+  */
+  closureGraph =
+    { drv }:
+    { query, ... }:
+    {
+      body = drv;
+      mode = "closure";
+      whyDepends = head (query.whyDepends or [ "" ]);
+    };
+
+  /**
+    Compare the closures of two derivations or store paths.
+    The added and removed packages, version changes and size deltas are rendered as JSON or HTML.
+  */
+  closureDiff =
+    { from, to }:
+    { ... }:
+    {
+      body = to;
+      diffBase = if isDerivation from then from.drvPath else toString from;
+      mode = "diff";
+    };
+
+  /**
+    Reverse proxy requests to a long-running service started from a derivation.
+    The service must listen on the Unix socket or TCP port passed via `@socket@` / `@port@` in its arguments
+    or the `NIXPRESSO_SOCKET` / `PORT` environment variables.
+  */
+  reverseProxy =
+    {
+      drv,
+      subPath ? "bin/${drv.meta.mainProgram or (lib.getName drv)}",
+      args ? [ ],
+      env ? { },
+      listen ? "unix",
+    }:
+    { ... }:
+    {
+      body = drv;
+      mode = "proxy";
+      inherit
+        subPath
+        args
+        env
+        listen
+        ;
+    };
+
+  /**
+    Run a program in a pseudo-terminal (PTY) and stream its output.
+    The size and type of the terminal are taken from the `rows`, `cols` & `term` query parameters
+    or the `X-Terminal-Rows`, `X-Terminal-Cols` & `X-Terminal-Type` request headers.
+    Invalid values are ignored in favor of the defaults.
+  */
+  runInTerminal =
+    {
+      drv,
+      subPath ? "bin/${drv.meta.mainProgram or (lib.getName drv)}",
+      args ? [ ],
+      env ? { },
+    }:
+    { query, headers, ... }:
+    let
+      param = name: header: head (query.${name} or headers.${header} or [ "" ]);
+      size = value: if match "[0-9]{1,4}" value != null then toInt value else 0;
+      termType = value: if match "[a-zA-Z0-9._+-]{1,64}" value != null then value else "";
+    in
+    {
+      body = drv;
+      mode = "run";
+      pty = true;
+      stream = true;
+      ptyRows = size (param "rows" "X-Terminal-Rows");
+      ptyCols = size (param "cols" "X-Terminal-Cols");
+      term = termType (param "term" "X-Terminal-Type");
+      inherit subPath args env;
+    };
+
+  /**
+    Serve the closure of a derivation or store path as a Nix binary cache.
+  */
+  binaryCache =
+    {
+      drv,
+      compression ? "zstd",
+    }:
+    { path, ... }:
+    {
+      body = drv;
+      subPath = path;
+      mode = "cache";
+      inherit compression;
+    };
+
   /**
     Render a HTML page.
   */
@@ -297,8 +500,15 @@ in
 
     router
     directoryIndex
+    directoryListing
 
     servePath
+    serveOutputs
+    closureGraph
+    closureDiff
+    binaryCache
+    reverseProxy
+    runInTerminal
     redirect
     html
     htmlError
@@ -50,6 +50,7 @@ let
     body = "";
     mode = "serve";
     output = "out";
+    outputs = [ ];
     headers = { };
     subPath = "";
     args = [ ];
@@ -57,7 +58,27 @@ let
     rebuild = false;
     recursive = false;
     pty = false;
+    ptyRows = 0;
+    ptyCols = 0;
+    term = "";
+    cgi = false;
     stream = false;
+    needBody = false;
+    archive = "";
+    compression = "";
+    immutable = false;
+    whyDepends = "";
+    diffBase = "";
+    listen = "unix";
+    service = "";
+    protocol = "";
+    workers = 1;
+    exitStatus = { };
+    sandbox = false;
+    capabilities = [ ];
+    seccompProfile = "";
+    secrets = { };
+    secretFiles = [ ];
   };
 
   metaDefaults = {
@@ -68,6 +89,7 @@ let
     };
 
     evalArgs = [ ];
+    deferBody = false;
   };
 
   withResponseDefaults =
@@ -130,7 +152,7 @@ let
       args = functionArgs handler;
 
       handlerWithBody =
-        { bodyHash, ... }@request:
+        { bodyHash ? null, ... }@request:
         let
           bodyDrv = fetchurl {
             name = "body";
@@ -139,7 +161,7 @@ let
           };
 
           requestWithBody = request // {
-            body = bodyDrv;
+            body = if bodyHash != null then bodyDrv else null;
           };
         in
         handler requestWithBody;
@@ -243,13 +265,19 @@ let
               "mode=${mode}"
             ]
             ++ optional pty "pty"
+            ++ optional (mode == "run" && cgi) "cgi"
+            ++ optional (mode == "run" && protocol != "") "protocol=${protocol}"
+            ++ optional (mode == "run" && sandbox) "sandbox"
+            ++ optional (mode == "run" && seccompProfile != "") "seccomp=${seccompProfile}"
             ++ optional inPureEvalMode "pure"
             ++ optional (!inPureEvalMode) "system=${builtins.currentSystem}"
             ++ optional (mode == "derivation" && recursive) "recursive"
             ++ optional (type == "derivation" && (mode == "log" || mode == "serve") && rebuild) "rebuild"
             ++ optionals (type == "derivation") [ "output=${output}" ]
+            ++ optional (type == "derivation" && outputs != [ ]) "outputs=${concatStringsSep "," outputs}"
             ++ optional (type == "path" || type == "derivation") "path=${body}"
             ++ optional (subPath != "") "subPath=${subPath}"
+            ++ optional (archive != "") "archive=${archive}"
           );
       in
       recursiveUpdate response {
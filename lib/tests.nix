@@ -1,13 +1,46 @@
 # SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
 # SPDX-License-Identifier: Apache-2.0
 
-{ lib, url }:
+{
+  lib,
+  url,
+  response,
+}:
 let
+  inherit (response) mkHandler;
+
+  deferredHandler = mkHandler { deferBody = true; } (
+    { body, ... }:
+    {
+      body = if body == null then "deferred" else "imported";
+    }
+  );
+
+  request = {
+    path = "/";
+    headers = { };
+    error = null;
+    options = {
+      allowedModes = [ "serve" ];
+      allowedTypes = [ "string" ];
+    };
+  };
+
   cases = lib.runTests {
     testUnescapeURL = {
       expr = url.unescape "hello";
       expected = "hello";
     };
+
+    testDeferredBodyWithoutHash = {
+      expr = (deferredHandler request).body;
+      expected = "deferred";
+    };
+
+    testDeferredBodyHashOptional = {
+      expr = (lib.functionArgs deferredHandler).bodyHash;
+      expected = true;
+    };
   };
 in
 if cases == [ ] then
@@ -85,6 +85,7 @@ let
           args = unique (old.evalCacheIgnore.args or [ ] ++ new.evalCacheIgnore.args or [ ]);
         };
         pty = (old.pty or false) || (new.pty or false);
+        deferBody = (old.deferBody or false) || (new.deferBody or false);
       }
     );
 
@@ -3,8 +3,18 @@
 
 package main
 
-import "github.com/stv0g/nixpresso/cmd"
+import (
+	"github.com/stv0g/nixpresso/cmd"
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/sandbox"
+	"github.com/stv0g/nixpresso/pkg/users"
+)
 
 func main() {
+	// Sandboxed and limited programs are started via a re-execution of Nixpresso
+	sandbox.Init()
+	limits.Init()
+	users.Init()
+
 	cmd.Execute()
 }
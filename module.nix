@@ -10,10 +10,12 @@
 }:
 let
   inherit (lib)
+    elem
     escapeShellArgs
     getExe
     last
     length
+    mapAttrsToList
     mkEnableOption
     mkIf
     mkOption
@@ -32,6 +34,23 @@ let
     in
     assert 2 == length parts;
     toInt (last parts);
+
+  # Quotas are enforced by cgroups which Nixpresso creates in its delegated subtree.
+  cgroups = with cfg.settings.limits; memory != null || cpu != null || processes != null;
+
+  # Sandboxed programs require mount and capability related system calls inside their own namespaces.
+  sandboxing = cfg.settings.sandbox == true || elem "run" cfg.settings.allowedModes;
+
+  # Programs running as dedicated users are started, killed and cleaned up with additional capabilities.
+  # Nixpresso clears its ambient capabilities in all child processes before they are executed.
+  dedicatedUsers = cfg.settings.runUsers != null;
+  dedicatedUsersCapabilities = optionals dedicatedUsers [
+    "CAP_SETUID"
+    "CAP_SETGID"
+    "CAP_CHOWN"
+    "CAP_DAC_OVERRIDE"
+    "CAP_KILL"
+  ];
 in
 {
   options = {
@@ -82,6 +101,11 @@ in
               "log"
               "derivation"
               "run"
+              "cache"
+              "listing"
+              "closure"
+              "diff"
+              "proxy"
             ]
           );
           default = [ ];
@@ -94,6 +118,7 @@ in
               "path"
               "derivation"
               "string"
+              "value"
             ]
           );
           default = [ ];
@@ -109,12 +134,114 @@ in
           default = [ ];
         };
 
+        sandbox = mkOption {
+          description = "Run all programs of the run mode in a sandbox of unprivileged Linux namespaces.";
+          type = types.nullOr types.bool;
+          default = null;
+        };
+
+        allowedCapabilities = mkOption {
+          description = "Sandbox capabilities which handlers are allowed to request. Either `network` or Linux capabilities.";
+          type = types.listOf types.str;
+          example = [
+            "network"
+            "CAP_NET_BIND_SERVICE"
+          ];
+          default = [ ];
+        };
+
+        runUsers = mkOption {
+          description = ''
+            Range of UIDs and GIDs from which a dedicated user with a private home directory is allocated for each program of the run mode.
+            Remaining processes of the user and its files in shared temporary directories are removed after the program exits.
+
+            The range must not overlap with other users of the system, including the dynamic users of systemd.
+          '';
+
+          type = types.nullOr types.str;
+          example = "100000-100999";
+          default = null;
+        };
+
+        secrets = mkOption {
+          description = ''
+            Secrets which handlers can inject into programs of the run mode by name.
+
+            They are loaded via systemd's `LoadCredential=` and never pass through Nix.
+          '';
+
+          type = types.attrsOf types.path;
+          example = {
+            github-token = "/run/keys/github-token";
+          };
+          default = { };
+        };
+
+        seccompProfiles = mkOption {
+          description = ''
+            JSON file with named seccomp profiles which handlers can select for programs of the run mode.
+
+            Each profile has a `defaultAction` and a list of `syscalls` rules with `names` and an `action`.
+            Actions are `allow`, `errno`, `log` and `kill`.
+          '';
+
+          type = types.nullOr types.path;
+          example = "/etc/nixpresso/seccomp.json";
+          default = null;
+        };
+
+        seccompProfile = mkOption {
+          description = ''
+            Name of the seccomp profile which is applied to all programs of the run mode.
+
+            The built-in `default` profile denies system calls like `ptrace`, `mount`, `kexec_load` and `bpf`.
+            The value `none` disables it.
+          '';
+
+          type = types.str;
+          example = "none";
+          default = "default";
+        };
+
         allowStore = mkOption {
           description = "Allow serving or executing content from the Nix store.";
           type = types.nullOr types.bool;
           default = null;
         };
 
+        cacheSecretKeyFile = mkOption {
+          description = "Secret key file used to sign narinfo files served in the cache mode.";
+          type = types.nullOr types.path;
+          example = "/var/nixpresso/cache-key.sec";
+          default = null;
+        };
+
+        dotPath = mkOption {
+          description = "Path to the GraphViz `dot` binary used to render closure graphs as SVG.";
+          type = types.nullOr types.path;
+          example = "/run/current-system/sw/bin/dot";
+          default = null;
+        };
+
+        openapiPath = mkOption {
+          description = "Path at which the OpenAPI document of the handler is served.";
+          type = types.nullOr types.str;
+          example = "/openapi.json";
+          default = null;
+        };
+
+        compressMinBytes = mkOption {
+          description = ''
+            Minimum size of compressible responses which are compressed on-the-fly.
+
+            A negative value disables on-the-fly compression.
+          '';
+
+          type = types.nullOr types.int;
+          example = 1024;
+          default = null;
+        };
+
         tls = {
           certificateFile = mkOption {
             description = "Path to the TLS certificate file.";
@@ -203,6 +330,64 @@ in
             example = "5m";
             default = null;
           };
+
+          proxyIdle = mkOption {
+            description = ''
+              Duration after which idle services started by the proxy mode or for FastCGI and SCGI applications are stopped.
+                          
+              A zero or negative value means they keep running.
+            '';
+
+            type = types.nullOr types.str;
+            example = "10m";
+            default = null;
+          };
+        };
+
+        limits = {
+          addressSpace = mkOption {
+            description = "Maximum size of the virtual memory of executed programs and Nix in bytes.";
+            type = types.nullOr types.int;
+            default = null;
+          };
+
+          cpuTime = mkOption {
+            description = "Maximum CPU time of executed programs and Nix.";
+            type = types.nullOr types.str;
+            example = "1m";
+            default = null;
+          };
+
+          openFiles = mkOption {
+            description = "Maximum number of open files of executed programs and Nix.";
+            type = types.nullOr types.int;
+            default = null;
+          };
+
+          processes = mkOption {
+            description = "Maximum number of processes of executed programs and Nix. Enforced by a delegated cgroup.";
+            type = types.nullOr types.int;
+            default = null;
+          };
+
+          fileSize = mkOption {
+            description = "Maximum size of files written by executed programs and Nix in bytes.";
+            type = types.nullOr types.int;
+            default = null;
+          };
+
+          memory = mkOption {
+            description = "Maximum memory of executed programs and Nix in bytes. Enforced by a delegated cgroup.";
+            type = types.nullOr types.int;
+            default = null;
+          };
+
+          cpu = mkOption {
+            description = "Maximum number of CPUs used by executed programs and Nix. Enforced by a delegated cgroup.";
+            type = types.nullOr types.number;
+            example = 0.5;
+            default = null;
+          };
         };
 
         maxSizes = {
@@ -293,22 +478,52 @@ in
                 max-eval-time = timeouts.eval;
                 max-build-time = timeouts.build;
                 max-run-time = timeouts.run;
+                proxy-idle-timeout = timeouts.proxyIdle;
+                limit-address-space = limits.addressSpace;
+                limit-cpu-time = limits.cpuTime;
+                limit-open-files = limits.openFiles;
+                limit-processes = limits.processes;
+                limit-file-size = limits.fileSize;
+                limit-memory = limits.memory;
+                limit-cpu = limits.cpu;
+                cgroup = if cgroups then "/sys/fs/cgroup/system.slice/nixpresso.service" else null;
                 max-request-bytes = maxSizes.request;
                 max-response-bytes = maxSizes.response;
+                compress-min-bytes = compressMinBytes;
                 allow-mode = allowedModes;
                 allow-type = allowedTypes;
                 allow-path = allowedPaths;
                 allow-store = allowStore;
+                sandbox = sandbox;
+                allow-capability = allowedCapabilities;
+                run-users = runUsers;
+                secrets-dir = if secrets != { } then "%d" else null;
+                seccomp-profiles = seccompProfiles;
+                seccomp-profile = seccompProfile;
+                cache-secret-key = cacheSecretKeyFile;
+                openapi-path = openapiPath;
+                dot = dotPath;
               })
               ++ cfg.settings.extraArgs
               ++ [ "--" ]
               ++ cfg.settings.nixArgs
             ));
 
+          Delegate = mkIf cgroups [
+            "memory"
+            "cpu"
+            "pids"
+          ];
+          DelegateSubgroup = mkIf cgroups "server";
+
+          LoadCredential = mapAttrsToList (name: path: "${name}:${path}") cfg.settings.secrets;
+
           DynamicUser = true;
           UMask = "0007";
-          CapabilityBoundingSet = optionals (listenPort < 1024) [ "CAP_NET_BIND_SERVICE" ];
-          AmbientCapabilities = optionals (listenPort < 1024) [ "CAP_NET_BIND_SERVICE" ];
+          CapabilityBoundingSet =
+            optionals (listenPort < 1024) [ "CAP_NET_BIND_SERVICE" ] ++ dedicatedUsersCapabilities;
+          AmbientCapabilities =
+            optionals (listenPort < 1024) [ "CAP_NET_BIND_SERVICE" ] ++ dedicatedUsersCapabilities;
           NoNewPrivileges = true;
           BindPaths = "/nix/";
 
@@ -318,7 +533,7 @@ in
           CacheDirectory = "nixpresso";
           PrivateTmp = true;
           PrivateDevices = true;
-          ProtectHostname = true;
+          ProtectHostname = !sandboxing;
           ProtectClock = true;
           ProtectKernelTunables = true;
           ProtectKernelModules = true;
@@ -331,7 +546,11 @@ in
           RestrictSUIDSGID = true;
           PrivateMounts = true;
           SystemCallArchitectures = "native";
-          SystemCallFilter = "~@clock @privileged @cpu-emulation @debug @keyring @module @mount @obsolete @raw-io @reboot @setuid @swap";
+          SystemCallFilter =
+            if sandboxing || dedicatedUsers then
+              "~@clock @cpu-emulation @debug @keyring @module @obsolete @raw-io @reboot @swap"
+            else
+              "~@clock @privileged @cpu-emulation @debug @keyring @module @mount @obsolete @raw-io @reboot @setuid @swap";
         };
       };
     };
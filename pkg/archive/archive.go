@@ -0,0 +1,265 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	FormatTar    = "tar"
+	FormatTarGz  = "tar.gz"
+	FormatTarZst = "tar.zst"
+	FormatZip    = "zip"
+)
+
+var AllFormats = []string{FormatTar, FormatTarGz, FormatTarZst, FormatZip}
+
+type ForbiddenPathError struct {
+	Path string
+}
+
+func (e *ForbiddenPathError) Error() string {
+	return fmt.Sprintf("forbidden path: %s", e.Path)
+}
+
+// ModTime is the modification time of all entries in an archive.
+// Like in the Nix store, it is one second after the epoch.
+var ModTime = time.Unix(1, 0).UTC()
+
+type Entry struct {
+	Name     string // Relative to the root of the archive
+	Path     string
+	Mode     fs.FileMode
+	Size     int64
+	Linkname string
+}
+
+// Walk collects the entries of an archive of the file or directory at root in lexical order.
+// The check function is called for every entry and the targets of symlinks which exist.
+func Walk(root string, check func(path string) bool) (entries []Entry, size int64, err error) {
+	base := filepath.Base(root)
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !check(path) {
+			return &ForbiddenPathError{Path: path}
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		e := Entry{
+			Name: filepath.ToSlash(filepath.Join(base, rel)),
+			Path: path,
+		}
+
+		switch {
+		case fi.Mode()&fs.ModeSymlink != 0:
+			if e.Linkname, err = os.Readlink(path); err != nil {
+				return fmt.Errorf("failed to read symlink '%s': %w", path, err)
+			}
+
+			// Dangling symlinks are archived as-is as they do not expose any content.
+			if target, err := filepath.EvalSymlinks(path); err == nil {
+				if !check(target) {
+					return &ForbiddenPathError{Path: target}
+				}
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("failed to evaluate symlink '%s': %w", path, err)
+			}
+
+			e.Mode = fs.ModeSymlink | 0o777
+
+		case fi.IsDir():
+			e.Mode = fs.ModeDir | 0o555
+
+		case fi.Mode().IsRegular():
+			e.Mode = 0o444
+			if fi.Mode()&0o111 != 0 {
+				e.Mode = 0o555
+			}
+
+			e.Size = fi.Size()
+			size += e.Size
+
+		default:
+			return fmt.Errorf("unsupported file type: %s", path)
+		}
+
+		entries = append(entries, e)
+
+		return nil
+	})
+
+	return entries, size, err
+}
+
+// Write streams an archive of the entries in the given format.
+func Write(wr io.Writer, format string, entries []Entry) error {
+	switch format {
+	case FormatTar:
+		return writeTar(wr, entries)
+
+	case FormatTarGz:
+		gw := gzip.NewWriter(wr)
+		if err := writeTar(gw, entries); err != nil {
+			return err
+		}
+
+		return gw.Close()
+
+	case FormatTarZst:
+		zw, err := zstd.NewWriter(wr)
+		if err != nil {
+			return fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+
+		if err := writeTar(zw, entries); err != nil {
+			zw.Close() //nolint:errcheck
+			return err
+		}
+
+		return zw.Close()
+
+	case FormatZip:
+		return writeZip(wr, entries)
+
+	default:
+		return fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+func ContentType(format string) string {
+	switch format {
+	case FormatTar:
+		return "application/x-tar"
+	case FormatTarGz:
+		return "application/gzip"
+	case FormatTarZst:
+		return "application/zstd"
+	case FormatZip:
+		return "application/zip"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+func IsValidFormat(format string) bool {
+	return slices.Contains(AllFormats, format)
+}
+
+func writeTar(wr io.Writer, entries []Entry) error {
+	tw := tar.NewWriter(wr)
+
+	for _, e := range entries {
+		hdr := &tar.Header{
+			Name:    e.Name,
+			Mode:    int64(e.Mode.Perm()),
+			ModTime: ModTime,
+			Format:  tar.FormatPAX,
+		}
+
+		switch {
+		case e.Mode&fs.ModeSymlink != 0:
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = e.Linkname
+
+		case e.Mode.IsDir():
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+
+		default:
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = e.Size
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write tar header: %w", err)
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			if err := copyFile(tw, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeZip(wr io.Writer, entries []Entry) error {
+	zw := zip.NewWriter(wr)
+
+	for _, e := range entries {
+		hdr := &zip.FileHeader{
+			Name:     e.Name,
+			Modified: ModTime,
+			Method:   zip.Deflate,
+		}
+
+		if e.Mode.IsDir() {
+			hdr.Name += "/"
+			hdr.Method = zip.Store
+		}
+
+		hdr.SetMode(e.Mode)
+
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			return fmt.Errorf("failed to write zip header: %w", err)
+		}
+
+		switch {
+		case e.Mode&fs.ModeSymlink != 0:
+			if _, err := io.WriteString(w, e.Linkname); err != nil {
+				return err
+			}
+
+		case e.Mode.IsRegular():
+			if err := copyFile(w, e); err != nil {
+				return err
+			}
+		}
+	}
+
+	return zw.Close()
+}
+
+func copyFile(wr io.Writer, e Entry) error {
+	f, err := os.Open(e.Path)
+	if err != nil {
+		return fmt.Errorf("failed to open '%s': %w", e.Path, err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	// Guard against files which have changed since walking the tree
+	if _, err := io.Copy(wr, io.LimitReader(f, e.Size)); err != nil {
+		return fmt.Errorf("failed to copy '%s': %w", e.Path, err)
+	}
+
+	return nil
+}
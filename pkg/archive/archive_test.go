@@ -0,0 +1,189 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package archive_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/archive"
+)
+
+func testTree(t *testing.T) string {
+	root := filepath.Join(t.TempDir(), "tree")
+
+	for name, content := range map[string]string{
+		"b.txt":     "b",
+		"a/c.txt":   "c",
+		"a/run.sh":  "#!/bin/sh",
+		"z/d/e.txt": "e",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		mode := os.FileMode(0o644)
+		if strings.HasSuffix(name, ".sh") {
+			mode = 0o755
+		}
+
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Symlink("../b.txt", filepath.Join(root, "a", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+func allowAll(string) bool { return true }
+
+func TestWalk(t *testing.T) {
+	root := testTree(t)
+
+	entries, size, err := archive.Walk(root, allowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+
+	expected := "tree tree/a tree/a/c.txt tree/a/link tree/a/run.sh tree/b.txt tree/z tree/z/d tree/z/d/e.txt"
+	if got := strings.Join(names, " "); got != expected {
+		t.Errorf("Names = %s, want %s", got, expected)
+	}
+
+	if size != 12 {
+		t.Errorf("Size = %d, want 12", size)
+	}
+}
+
+func TestWalkForbiddenSymlink(t *testing.T) {
+	root := testTree(t)
+	outside := filepath.Join(filepath.Dir(root), "outside")
+
+	if err := os.WriteFile(outside, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err := archive.Walk(root, func(path string) bool {
+		return strings.HasPrefix(path, root)
+	})
+
+	var fpe *archive.ForbiddenPathError
+	if !errors.As(err, &fpe) || fpe.Path != outside {
+		t.Errorf("Expected forbidden path error for %s, got %v", outside, err)
+	}
+}
+
+func TestWalkDanglingSymlink(t *testing.T) {
+	root := testTree(t)
+
+	if err := os.Symlink("missing", filepath.Join(root, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _, err := archive.Walk(root, func(path string) bool {
+		return strings.HasPrefix(path, root)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range entries {
+		if e.Name == "tree/dangling" {
+			if e.Mode&os.ModeSymlink == 0 || e.Linkname != "missing" {
+				t.Errorf("Unexpected entry for dangling symlink: %+v", e)
+			}
+
+			return
+		}
+	}
+
+	t.Error("Dangling symlink has not been archived")
+}
+
+func TestWriteDeterministic(t *testing.T) {
+	for _, format := range archive.AllFormats {
+		t.Run(format, func(t *testing.T) {
+			var outputs [2]bytes.Buffer
+
+			for i := range outputs {
+				// Recreate the tree to get different modification times
+				entries, _, err := archive.Walk(testTree(t), allowAll)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := archive.Write(&outputs[i], format, entries); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !bytes.Equal(outputs[0].Bytes(), outputs[1].Bytes()) {
+				t.Error("Archives are not identical")
+			}
+		})
+	}
+}
+
+func TestWriteTar(t *testing.T) {
+	entries, _, err := archive.Walk(testTree(t), allowAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := archive.Write(&buf, archive.FormatTar, entries); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := tar.NewReader(&buf)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+
+		if !hdr.ModTime.Equal(archive.ModTime) {
+			t.Errorf("%s: ModTime = %v, want %v", hdr.Name, hdr.ModTime, archive.ModTime)
+		}
+
+		switch hdr.Name {
+		case "tree/a/run.sh":
+			if hdr.Mode != 0o555 {
+				t.Errorf("%s: Mode = %o, want 555", hdr.Name, hdr.Mode)
+			}
+
+		case "tree/a/link":
+			if hdr.Typeflag != tar.TypeSymlink || hdr.Linkname != "../b.txt" {
+				t.Errorf("%s: invalid symlink", hdr.Name)
+			}
+
+		case "tree/b.txt":
+			if hdr.Mode != 0o444 {
+				t.Errorf("%s: Mode = %o, want 444", hdr.Name, hdr.Mode)
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// See: https://fastcgi-archives.github.io/FastCGI_Specification.html
+
+const (
+	fcgiVersion = 1
+
+	fcgiBeginRequest = 1
+	fcgiEndRequest   = 3
+	fcgiParams       = 4
+	fcgiStdin        = 5
+	fcgiStdout       = 6
+	fcgiStderr       = 7
+
+	fcgiResponder = 1
+
+	fcgiRequestComplete = 0
+
+	fcgiRequestID = 1
+
+	fcgiMaxContent = 65535
+)
+
+var fcgiProtocolStatus = map[byte]string{
+	1: "cannot multiplex connections",
+	2: "overloaded",
+	3: "unknown role",
+}
+
+var ErrFastCGIIncomplete = errors.New("FastCGI application closed connection before completing the request")
+
+type fcgiHeader struct {
+	Version       uint8
+	Type          uint8
+	RequestID     uint16
+	ContentLength uint16
+	PaddingLength uint8
+	Reserved      uint8
+}
+
+// FastCGI performs a single request in the responder role.
+// The CGI-style output of the application is written to stdout and stderr.
+// It returns the application status from the end of the request.
+// Sending the remaining body is interrupted once the application has responded.
+func FastCGI(conn io.ReadWriteCloser, params []string, stdin io.Reader, stdout, stderr io.Writer) (appStatus int, err error) {
+	wr := bufio.NewWriter(conn)
+
+	if err := writeRecord(wr, fcgiBeginRequest, []byte{0, fcgiResponder, 0, 0, 0, 0, 0, 0}); err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	for _, param := range params {
+		name, value, err := splitParam(param)
+		if err != nil {
+			return 0, err
+		}
+
+		writeLength(&buf, len(name))
+		writeLength(&buf, len(value))
+		buf.WriteString(name)
+		buf.WriteString(value)
+	}
+
+	if err := writeStream(wr, fcgiParams, &buf); err != nil {
+		return 0, err
+	}
+
+	if err := wr.Flush(); err != nil {
+		return 0, err
+	}
+
+	if stdin == nil {
+		stdin = &bytes.Buffer{}
+	}
+
+	// Errors while sending the remaining body are irrelevant once the application has responded.
+	wait := sendBody(conn, stdin, func() error {
+		if err := writeStream(wr, fcgiStdin, stdin); err != nil {
+			return err
+		}
+
+		return wr.Flush()
+	})
+	defer wait() //nolint:errcheck
+
+	rd := bufio.NewReader(conn)
+	for {
+		var hdr fcgiHeader
+		if err := binary.Read(rd, binary.BigEndian, &hdr); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return 0, ErrFastCGIIncomplete
+			}
+
+			return 0, fmt.Errorf("failed to read record: %w", err)
+		}
+
+		content := make([]byte, int(hdr.ContentLength)+int(hdr.PaddingLength))
+		if _, err := io.ReadFull(rd, content); err != nil {
+			return 0, fmt.Errorf("failed to read record: %w", err)
+		}
+		content = content[:hdr.ContentLength]
+
+		if hdr.RequestID != fcgiRequestID {
+			continue
+		}
+
+		switch hdr.Type {
+		case fcgiStdout:
+			if _, err := stdout.Write(content); err != nil {
+				return 0, err
+			}
+
+		case fcgiStderr:
+			if _, err := stderr.Write(content); err != nil {
+				return 0, err
+			}
+
+		case fcgiEndRequest:
+			if len(content) < 8 {
+				return 0, fmt.Errorf("invalid end request record")
+			}
+
+			if status := content[4]; status != fcgiRequestComplete {
+				return 0, fmt.Errorf("FastCGI application rejected request: %s", fcgiProtocolStatus[status])
+			}
+
+			return int(binary.BigEndian.Uint32(content[:4])), nil
+		}
+	}
+}
+
+func writeRecord(wr io.Writer, typ uint8, content []byte) error {
+	padding := -len(content) & 7
+
+	hdr := fcgiHeader{
+		Version:       fcgiVersion,
+		Type:          typ,
+		RequestID:     fcgiRequestID,
+		ContentLength: uint16(len(content)),
+		PaddingLength: uint8(padding),
+	}
+
+	if err := binary.Write(wr, binary.BigEndian, hdr); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	if _, err := wr.Write(content); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	if _, err := wr.Write(make([]byte, padding)); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return nil
+}
+
+// writeStream splits the content of a stream into records which are terminated by an empty record.
+func writeStream(wr io.Writer, typ uint8, rd io.Reader) error {
+	buf := make([]byte, fcgiMaxContent)
+	for {
+		n, err := rd.Read(buf)
+		if n > 0 {
+			if err := writeRecord(wr, typ, buf[:n]); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("failed to read stream: %w", err)
+		}
+	}
+
+	return writeRecord(wr, typ, nil)
+}
+
+func writeLength(buf *bytes.Buffer, n int) {
+	if n < 128 {
+		buf.WriteByte(byte(n))
+	} else {
+		binary.Write(buf, binary.BigEndian, uint32(n)|1<<31) //nolint:errcheck
+	}
+}
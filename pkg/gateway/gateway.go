@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gateway implements clients for the FastCGI and SCGI protocols.
+// Both pass the request as CGI meta-variables and return CGI-style responses.
+package gateway
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	ProtocolFastCGI = "fastcgi"
+	ProtocolSCGI    = "scgi"
+)
+
+var AllProtocols = []string{ProtocolFastCGI, ProtocolSCGI}
+
+func splitParam(param string) (name, value string, err error) {
+	name, value, ok := strings.Cut(param, "=")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid parameter: %q", param)
+	}
+
+	return name, value, nil
+}
+
+// sendBody sends the request body concurrently as applications may respond before consuming it.
+// The returned function waits for the body to be sent. If it is still pending, it is interrupted
+// by closing the connection and the body, if it implements io.Closer.
+func sendBody(conn io.Closer, stdin io.Reader, send func() error) (wait func() error) {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- send()
+	}()
+
+	return func() error {
+		select {
+		case err := <-errs:
+			return err
+		default:
+		}
+
+		conn.Close() //nolint:errcheck
+		if c, ok := stdin.(io.Closer); ok {
+			c.Close() //nolint:errcheck
+		}
+
+		return <-errs
+	}
+}
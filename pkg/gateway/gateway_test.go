@@ -0,0 +1,184 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package gateway_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/gateway"
+)
+
+var params = []string{
+	"REQUEST_METHOD=POST",
+	"SERVER_PROTOCOL=HTTP/1.1",
+	"REQUEST_URI=/foo?a=1",
+	"QUERY_STRING=a=1",
+	"PATH_INFO=/foo",
+	"SERVER_NAME=example.com",
+	"SERVER_PORT=80",
+	"CONTENT_LENGTH=5",
+	"HTTP_X_LONG=" + strings.Repeat("x", 300),
+}
+
+func listen(t *testing.T) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { l.Close() }) //nolint:errcheck
+
+	return l
+}
+
+func TestFastCGI(t *testing.T) {
+	l := listen(t)
+
+	go fcgi.Serve(l, http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) { //nolint:errcheck
+		body, _ := io.ReadAll(req.Body)
+
+		wr.Header().Set("Content-Type", "text/x-test")
+		wr.WriteHeader(http.StatusTeapot)
+
+		fmt.Fprintf(wr, "%s %s %s %d %s", req.Method, req.URL.Path, req.URL.Query().Get("a"), len(req.Header.Get("X-Long")), body)
+	}))
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	var stdout, stderr bytes.Buffer
+	status, err := gateway.FastCGI(conn, params, strings.NewReader("hello"), &stdout, &stderr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status != 0 {
+		t.Errorf("status = %d", status)
+	}
+
+	resp := stdout.String()
+	if !strings.Contains(resp, "Status: 418") || !strings.Contains(resp, "Content-Type: text/x-test") {
+		t.Errorf("unexpected headers: %q", resp)
+	}
+
+	if !strings.HasSuffix(resp, "\r\n\r\nPOST /foo 1 300 hello") {
+		t.Errorf("unexpected body: %q", resp)
+	}
+}
+
+func TestSCGI(t *testing.T) {
+	l := listen(t)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() //nolint:errcheck
+
+		rd := bufio.NewReader(conn)
+
+		length, _ := rd.ReadString(':')
+		n, _ := strconv.Atoi(strings.TrimSuffix(length, ":"))
+
+		netstring := make([]byte, n+1)
+		io.ReadFull(rd, netstring) //nolint:errcheck
+
+		fields := strings.Split(string(netstring[:n]), "\x00")
+		hdrs := map[string]string{}
+		for i := 0; i+1 < len(fields); i += 2 {
+			hdrs[fields[i]] = fields[i+1]
+		}
+
+		cl, _ := strconv.Atoi(hdrs["CONTENT_LENGTH"])
+		body := make([]byte, cl)
+		io.ReadFull(rd, body) //nolint:errcheck
+
+		fmt.Fprintf(conn, "Status: 200 OK\r\nContent-Type: text/plain\r\n\r\n%s %s %s %s", fields[0], hdrs["SCGI"], hdrs["PATH_INFO"], body)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	var stdout bytes.Buffer
+
+	// Unknown content length is determined by buffering the body
+	if err := gateway.SCGI(conn, params, strings.NewReader("hello"), -1, 0, &stdout); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := stdout.String(); !strings.HasSuffix(resp, "\r\n\r\nCONTENT_LENGTH 1 /foo hello") {
+		t.Errorf("unexpected response: %q", resp)
+	}
+}
+
+func TestSCGIUnreadBody(t *testing.T) {
+	l := listen(t)
+
+	// The application responds without consuming the body
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() //nolint:errcheck
+
+		rd := bufio.NewReader(conn)
+
+		length, _ := rd.ReadString(':')
+		n, _ := strconv.Atoi(strings.TrimSuffix(length, ":"))
+		io.ReadFull(rd, make([]byte, n+1)) //nolint:errcheck
+
+		fmt.Fprint(conn, "Status: 200 OK\r\n\r\nearly")
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	// The body is never completed by the client
+	stdin, stdinWr := io.Pipe()
+
+	var stdout bytes.Buffer
+	if err := gateway.SCGI(conn, params, stdin, 5, 0, &stdout); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(stdout.String(), "early") {
+		t.Errorf("unexpected response: %q", stdout.String())
+	}
+
+	// The pending body has been interrupted before returning
+	if _, err := stdinWr.Write([]byte("late")); !errors.Is(err, io.ErrClosedPipe) {
+		t.Errorf("body has not been closed: %v", err)
+	}
+}
+
+func TestSCGIBodyTooLarge(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close() //nolint:errcheck
+
+	var stdout bytes.Buffer
+	if err := gateway.SCGI(client, params, strings.NewReader("hello"), -1, 4, &stdout); !errors.Is(err, gateway.ErrSCGIBodyTooLarge) {
+		t.Errorf("expected error for large body, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package gateway
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+var ErrSCGIBodyTooLarge = errors.New("request body of unknown length exceeds the maximum size for SCGI")
+
+// SCGI performs a single request and writes the CGI-style response of the application to stdout.
+// Bodies of unknown length are buffered up to maxBodySize as SCGI requires their length upfront.
+// A zero maxBodySize means there will be no limit.
+// Sending the remaining body is interrupted once the application has responded.
+// See: https://python.ca/scgi/protocol.txt
+func SCGI(conn io.ReadWriteCloser, params []string, stdin io.Reader, contentLength, maxBodySize int64, stdout io.Writer) error {
+	if stdin == nil {
+		stdin = &bytes.Buffer{}
+		contentLength = 0
+	} else if contentLength < 0 {
+		rd := stdin
+		if maxBodySize > 0 {
+			rd = io.LimitReader(stdin, maxBodySize+1)
+		}
+
+		body, err := io.ReadAll(rd)
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		} else if maxBodySize > 0 && int64(len(body)) > maxBodySize {
+			return ErrSCGIBodyTooLarge
+		}
+
+		stdin = bytes.NewReader(body)
+		contentLength = int64(len(body))
+	}
+
+	// CONTENT_LENGTH must be the first header
+	var hdrs bytes.Buffer
+	hdrs.WriteString("CONTENT_LENGTH\x00" + strconv.FormatInt(contentLength, 10) + "\x00")
+	hdrs.WriteString("SCGI\x001\x00")
+
+	for _, param := range params {
+		name, value, err := splitParam(param)
+		if err != nil {
+			return err
+		}
+
+		if name == "CONTENT_LENGTH" || name == "SCGI" {
+			continue
+		}
+
+		hdrs.WriteString(name + "\x00" + value + "\x00")
+	}
+
+	if _, err := fmt.Fprintf(conn, "%d:%s,", hdrs.Len(), hdrs.Bytes()); err != nil {
+		return fmt.Errorf("failed to write request: %w", err)
+	}
+
+	wait := sendBody(conn, stdin, func() error {
+		_, err := io.CopyN(conn, stdin, contentLength)
+		return err
+	})
+	defer wait() //nolint:errcheck
+
+	if _, err := io.Copy(stdout, conn); err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+
+	"github.com/stv0g/nixpresso/pkg/archive"
+)
+
+func (r *Request) serveArchive() error {
+	if !archive.IsValidFormat(r.result.Archive) {
+		return fmt.Errorf("unsupported archive format: %s", r.result.Archive)
+	}
+
+	entries, size, err := archive.Walk(r.body, r.handler.checkPath)
+	if err != nil {
+		var fpe *archive.ForbiddenPathError
+		if errors.As(err, &fpe) {
+			return ForbiddenPathError(fpe.Path)
+		}
+
+		return fmt.Errorf("failed to collect archive entries: %w", err)
+	}
+
+	if size > r.handler.opts.MaxResponseBytes {
+		return fmt.Errorf("response body exceeds maximum size: %d > %d Bytes", size, r.handler.opts.MaxResponseBytes)
+	}
+
+	hdr := r.response.Header()
+	if hdr.Get("Content-Type") == "" {
+		hdr.Set("Content-Type", archive.ContentType(r.result.Archive))
+	}
+
+	if hdr.Get("Content-Disposition") == "" {
+		hdr.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filepath.Base(r.body), r.result.Archive))
+	}
+
+	r.writeHeader(r.result.Status)
+
+	if r.request.Method == http.MethodHead {
+		return nil
+	}
+
+	slog.Debug("Streaming archive",
+		slog.String("path", r.body),
+		slog.String("format", r.result.Archive),
+		slog.Int("entries", len(entries)),
+		slog.Int64("size", size))
+
+	if err := archive.Write(r.response, r.result.Archive, entries); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+
+	return nil
+}
@@ -4,8 +4,11 @@
 package handler
 
 import (
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net"
@@ -14,12 +17,17 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/stv0g/nixpresso/pkg/nix"
 	"github.com/stv0g/nixpresso/pkg/options"
 	"github.com/stv0g/nixpresso/pkg/util"
 )
 
+// Per-request arguments which differ for every request.
+// Handlers expecting any of them bypass the evaluation cache.
+var volatileArgs = []string{"now", "requestId", "nonce"}
+
 type Arguments struct {
 	// Request
 	Proto      *string          `json:"proto,omitempty"`
@@ -38,6 +46,11 @@ type Arguments struct {
 	Options  *options.Options `json:"options,omitempty"`
 	BasePath *string          `json:"basePath,omitempty"`
 
+	// Derived
+	Now       *int64  `json:"now,omitempty"`
+	RequestID *string `json:"requestId,omitempty"`
+	Nonce     *string `json:"nonce,omitempty"`
+
 	// Error handling
 	Error  *Error      `json:"error"`
 	Result *EvalResult `json:"result,omitempty"`
@@ -82,14 +95,10 @@ func (h *Handler) ArgumentsFromRequest(req *http.Request) (args Arguments, err e
 		args.TLS = convertConnectionState(req.TLS)
 	}
 
-	if _, ok := h.InspectResult.ExpectedArgs["bodyHash"]; ok {
-		hash, path, err := nix.AddToStore(req.Context(), req.Body, "body")
-		if err != nil {
-			return args, fmt.Errorf("failed to add body to store: %w", err)
+	if _, ok := h.InspectResult.ExpectedArgs["bodyHash"]; ok && !h.InspectResult.DeferBody {
+		if err := args.addBody(req); err != nil {
+			return args, err
 		}
-
-		args.BodyHash = &hash
-		args.Body = &path
 	}
 
 	if _, ok := h.InspectResult.ExpectedArgs["options"]; ok {
@@ -100,9 +109,53 @@ func (h *Handler) ArgumentsFromRequest(req *http.Request) (args Arguments, err e
 		args.BasePath = &h.opts.BasePath
 	}
 
+	if _, ok := h.InspectResult.ExpectedArgs["now"]; ok {
+		now := time.Now().Unix()
+		args.Now = &now
+	}
+
+	if _, ok := h.InspectResult.ExpectedArgs["requestId"]; ok {
+		id, err := randomString(16, hex.EncodeToString)
+		if err != nil {
+			return args, fmt.Errorf("failed to generate request ID: %w", err)
+		}
+
+		args.RequestID = &id
+	}
+
+	if _, ok := h.InspectResult.ExpectedArgs["nonce"]; ok {
+		nonce, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+		if err != nil {
+			return args, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+
+		args.Nonce = &nonce
+	}
+
 	return args, nil
 }
 
+func (a *Arguments) addBody(req *http.Request) error {
+	hash, path, err := nix.AddToStore(req.Context(), req.Body, "body")
+	if err != nil {
+		return fmt.Errorf("failed to add body to store: %w", err)
+	}
+
+	a.BodyHash = &hash
+	a.Body = &path
+
+	return nil
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return encode(b), nil
+}
+
 func (a *Arguments) Request() (req *http.Request, err error) {
 	var body io.ReadCloser
 
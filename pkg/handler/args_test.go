@@ -0,0 +1,81 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/cache"
+)
+
+func TestArgumentsVolatile(t *testing.T) {
+	h := &Handler{
+		InspectResult: InspectResult{
+			ExpectedArgs: map[string]bool{"now": true, "requestId": true, "nonce": true},
+		},
+	}
+
+	args1, err := h.ArgumentsFromRequest(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args2, err := h.ArgumentsFromRequest(httptest.NewRequest("GET", "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args1.Now == nil || time.Since(time.Unix(*args1.Now, 0)) > time.Minute {
+		t.Errorf("Unexpected timestamp: %v", args1.Now)
+	}
+
+	if args1.RequestID == nil || !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(*args1.RequestID) {
+		t.Errorf("Unexpected request ID: %v", args1.RequestID)
+	}
+
+	if args1.Nonce == nil || !regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`).MatchString(*args1.Nonce) {
+		t.Errorf("Unexpected nonce: %v", args1.Nonce)
+	}
+
+	if *args1.RequestID == *args2.RequestID || *args1.Nonce == *args2.Nonce {
+		t.Error("Request IDs and nonces must differ between requests")
+	}
+}
+
+func TestCanEvalCacheVolatile(t *testing.T) {
+	evalCache, err := cache.NewMemoryCache[cache.NamedStringKey, *EvalResult](16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		expected map[string]bool
+		cachable bool
+	}{
+		{"none", map[string]bool{"path": true}, true},
+		{"now", map[string]bool{"path": true, "now": true}, false},
+		{"requestId", map[string]bool{"requestId": false}, false},
+		{"nonce", map[string]bool{"nonce": true}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Request{
+				handler: &Handler{
+					InspectResult: InspectResult{
+						ExpectedArgs: tc.expected,
+					},
+					cache: evalCache,
+				},
+				request: httptest.NewRequest("GET", "/", nil),
+			}
+
+			if cachable := r.canEvalCache(); cachable != tc.cachable {
+				t.Errorf("Unexpected cachability: %t", cachable)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stv0g/nixpresso/pkg/nix"
+	"github.com/stv0g/nixpresso/pkg/options"
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+var narExtensions = map[string]string{
+	CompressionNone: ".nar",
+	CompressionGzip: ".nar.gz",
+	CompressionZstd: ".nar.zst",
+}
+
+func (r *Request) binaryCache() error {
+	if r.result.Type != options.DerivationType && r.result.Type != options.PathType {
+		return fmt.Errorf("invalid combination of type and mode")
+	}
+
+	if !r.handler.checkPath(r.body) || !strings.HasPrefix(r.body, r.handler.env.StoreDir) {
+		return ForbiddenPathError(r.body)
+	}
+
+	compression := r.result.Compression
+	if compression == "" {
+		compression = CompressionNone
+	}
+
+	ext, ok := narExtensions[compression]
+	if !ok {
+		return fmt.Errorf("unsupported compression: %s", compression)
+	}
+
+	file := strings.TrimPrefix(r.result.SubPath, "/")
+
+	if file == "nix-cache-info" {
+		info := fmt.Sprintf("StoreDir: %s\nWantMassQuery: 1\nPriority: 50\n", r.handler.env.StoreDir)
+
+		r.response.Header().Set("Content-Type", "text/x-nix-cache-info")
+		r.writeHeader(0) // WriteHeader() is called by ServeContent()
+		http.ServeContent(r.response, r.request, file, time.Time{}, strings.NewReader(info))
+
+		return nil
+	}
+
+	var hashPart string
+	if hp, ok := strings.CutSuffix(file, ".narinfo"); ok && !strings.Contains(hp, "/") {
+		hashPart = hp
+	} else if hp, ok := strings.CutPrefix(file, "nar/"); ok && strings.HasSuffix(hp, ext) {
+		hashPart = strings.TrimSuffix(hp, ext)
+	} else {
+		return r.binaryCacheNotFound(file)
+	}
+
+	// Only paths in the closure of the result are served
+	closure, err := nix.PathInfos(r.request.Context(), r.handler.opts.Verbose, true, r.body)
+	if err != nil {
+		return fmt.Errorf("failed to get closure: %w", err)
+	}
+
+	var info *nix.PathInfo
+	for _, pi := range closure {
+		if pi.HashPart() == hashPart {
+			info = &pi
+			break
+		}
+	}
+
+	if info == nil {
+		return r.binaryCacheNotFound(file)
+	}
+
+	if strings.HasSuffix(file, ".narinfo") {
+		narInfo, err := info.NarInfo("nar/"+hashPart+ext, compression, r.handler.cacheKey)
+		if err != nil {
+			return fmt.Errorf("failed to render narinfo: %w", err)
+		}
+
+		r.response.Header().Set("Content-Type", "text/x-nix-narinfo")
+		r.writeHeader(0) // WriteHeader() is called by ServeContent()
+		http.ServeContent(r.response, r.request, file, time.Time{}, strings.NewReader(narInfo))
+
+		return nil
+	}
+
+	if info.NarSize > r.handler.opts.MaxResponseBytes {
+		return fmt.Errorf("response body exceeds maximum size: %d > %d Bytes", info.NarSize, r.handler.opts.MaxResponseBytes)
+	}
+
+	hdr := r.response.Header()
+	hdr.Set("Content-Type", "application/x-nix-nar")
+	if compression == CompressionNone {
+		hdr.Set("Content-Length", fmt.Sprint(info.NarSize))
+	}
+
+	r.writeHeader(http.StatusOK)
+
+	if r.request.Method == http.MethodHead {
+		return nil
+	}
+
+	slog.Debug("Streaming NAR",
+		slog.String("path", info.Path),
+		slog.String("compression", compression))
+
+	return writeCompressed(r.response, compression, func(wr io.Writer) error {
+		return nix.WriteNAR(wr, info.Path)
+	})
+}
+
+func (r *Request) binaryCacheNotFound(file string) error {
+	hdr := r.response.Header()
+	hdr.Set("Content-Type", "text/plain; charset=utf-8")
+
+	r.writeHeader(http.StatusNotFound)
+
+	_, err := fmt.Fprintf(r.response, "%s not found in binary cache\n", file)
+
+	return err
+}
+
+func writeCompressed(wr io.Writer, compression string, cb func(wr io.Writer) error) error {
+	var cw io.WriteCloser
+
+	switch compression {
+	case CompressionNone:
+		return cb(wr)
+
+	case CompressionGzip:
+		cw = gzip.NewWriter(wr)
+
+	case CompressionZstd:
+		var err error
+		if cw, err = zstd.NewWriter(wr); err != nil {
+			return fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+
+	default:
+		return fmt.Errorf("unsupported compression: %s", compression)
+	}
+
+	if err := cb(cw); err != nil {
+		cw.Close() //nolint:errcheck
+		return err
+	}
+
+	return cw.Close()
+}
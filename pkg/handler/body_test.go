@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestArgumentsDeferBody(t *testing.T) {
+	h := &Handler{
+		InspectResult: InspectResult{
+			ExpectedArgs: map[string]bool{"bodyHash": true},
+			DeferBody:    true,
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("hello"))
+
+	args, err := h.ArgumentsFromRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args.BodyHash != nil || args.Body != nil {
+		t.Fatal("Deferred body has been imported")
+	}
+
+	// The body must still be available to be imported when the handler sets needBody or to be streamed.
+	if b, err := io.ReadAll(req.Body); err != nil || string(b) != "hello" {
+		t.Fatalf("Deferred body has been consumed: %q, %v", b, err)
+	}
+}
+
+func TestArgumentsNeedBody(t *testing.T) {
+	if _, err := exec.LookPath("nix"); err != nil {
+		t.Skip("Nix is not available")
+	}
+
+	h := &Handler{
+		InspectResult: InspectResult{
+			ExpectedArgs: map[string]bool{"bodyHash": true},
+		},
+	}
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader("hello"))
+
+	args, err := h.ArgumentsFromRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if args.BodyHash == nil || args.Body == nil {
+		t.Fatal("Body has not been imported")
+	}
+
+	if b, err := os.ReadFile(*args.Body); err != nil || string(b) != "hello" {
+		t.Fatalf("Unexpected imported body: %q, %v", b, err)
+	}
+}
+
+func TestImportedBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body")
+	if err := os.WriteFile(path, []byte("imported"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		body     *string
+		expected string
+	}{
+		{"streamed", nil, ""},
+		{"imported", &path, "imported"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Request{
+				request: httptest.NewRequest("POST", "/", strings.NewReader("streamed")),
+				arguments: Arguments{
+					Body: tc.body,
+				},
+				result: &EvalResult{},
+			}
+
+			body, err := r.importedBody()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expected == "" {
+				if body != nil {
+					t.Fatal("Expected body to be streamed")
+				}
+
+				return
+			}
+			defer body.Close() //nolint:errcheck
+
+			if b, err := io.ReadAll(body); err != nil || string(b) != tc.expected {
+				t.Fatalf("Unexpected body: %q, %v", b, err)
+			}
+		})
+	}
+}
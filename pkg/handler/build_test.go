@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestSelectOutput(t *testing.T) {
+	paths := map[string]string{
+		"out": "/nix/store/aaaa-hello-1.0",
+		"dev": "/nix/store/bbbb-hello-1.0-dev",
+		"doc": "/nix/store/cccc-hello-1.0-doc",
+	}
+
+	for _, tc := range []struct {
+		name         string
+		result       EvalResult
+		postProcess  bool
+		expectedBody string
+		expectedErr  bool
+	}{
+		{"default", EvalResult{Body: "/nix/store/x.drv"}, false, paths["out"], false},
+		{"selected", EvalResult{Body: "/nix/store/x.drv", Output: "dev", Outputs: []string{"dev", "doc"}}, false, paths["dev"], false},
+		{"missing", EvalResult{Body: "/nix/store/x.drv", Output: "lib"}, false, "", true},
+		{"post-process", EvalResult{Body: "/nix/store/x.drv", Output: "lib", Outputs: []string{"*"}}, true, "/nix/store/x.drv", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			expectedArgs := map[string]bool{}
+			if tc.postProcess {
+				expectedArgs["result"] = true
+			}
+
+			cached := tc.result
+			r := &Request{
+				handler: &Handler{
+					InspectResult: InspectResult{
+						ExpectedArgs: expectedArgs,
+					},
+				},
+				result: &cached,
+				body:   cached.Body,
+			}
+
+			err := r.selectOutput(paths)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if r.body != tc.expectedBody {
+				t.Errorf("expected body %q, got %q", tc.expectedBody, r.body)
+			}
+
+			if !maps.Equal(r.result.OutputPaths, paths) {
+				t.Errorf("unexpected output paths: %v", r.result.OutputPaths)
+			}
+
+			if r.result == &cached || cached.OutputPaths != nil {
+				t.Error("cached evaluation result has been modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg"
+)
+
+const maxCGIHeaderBytes = 64 << 10
+
+// CGIEnvironment returns the meta-variables of RFC 3875 for a request.
+// The script name is stripped from the request path to obtain "PATH_INFO".
+// "CONTENT_LENGTH" is omitted for requests without or with an unknown length of the body.
+func CGIEnvironment(req *http.Request, scriptName string, contentLength int64) []string {
+	scriptName = strings.TrimSuffix(scriptName, "/")
+	pathInfo := strings.TrimPrefix(req.URL.Path, scriptName)
+
+	env := []string{
+		"GATEWAY_INTERFACE=CGI/1.1",
+		"SERVER_SOFTWARE=Nixpresso/" + pkg.Version,
+		"SERVER_PROTOCOL=" + req.Proto,
+		"REQUEST_METHOD=" + req.Method,
+		"REQUEST_URI=" + req.URL.RequestURI(),
+		"QUERY_STRING=" + req.URL.RawQuery,
+		"SCRIPT_NAME=" + scriptName,
+		"PATH_INFO=" + pathInfo,
+	}
+
+	host, port, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+		if req.TLS != nil {
+			port = "443"
+		} else {
+			port = "80"
+		}
+	}
+
+	env = append(env, "SERVER_NAME="+host, "SERVER_PORT="+port)
+
+	if addr, port, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		env = append(env, "REMOTE_ADDR="+addr, "REMOTE_HOST="+addr, "REMOTE_PORT="+port)
+	} else {
+		env = append(env, "REMOTE_ADDR="+req.RemoteAddr, "REMOTE_HOST="+req.RemoteAddr)
+	}
+
+	if req.TLS != nil {
+		env = append(env, "HTTPS=on")
+	}
+
+	// Programs need the search path of the system to find interpreters and tools (RFC 3875, section 4.1.18).
+	if path := os.Getenv("PATH"); path != "" {
+		env = append(env, "PATH="+path)
+	}
+
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		env = append(env, "CONTENT_TYPE="+ct)
+	}
+
+	if contentLength > 0 {
+		env = append(env, fmt.Sprintf("CONTENT_LENGTH=%d", contentLength))
+	}
+
+	names := []string{}
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		// Underscores would make "Content_Type" indistinguishable from "Content-Type" in the environment.
+		if strings.Contains(name, "_") {
+			continue
+		}
+
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+
+		switch key {
+		case "CONTENT_TYPE", "CONTENT_LENGTH":
+			continue
+
+		// Mitigate "httpoxy" (CVE-2016-5385)
+		case "PROXY":
+			continue
+		}
+
+		sep := ", "
+		if key == "COOKIE" {
+			sep = "; "
+		}
+
+		env = append(env, "HTTP_"+key+"="+strings.Join(req.Header.Values(name), sep))
+	}
+
+	if req.Host != "" {
+		env = append(env, "HTTP_HOST="+req.Host)
+	}
+
+	return env
+}
+
+// ParseCGIHeader parses the header lines which a CGI program writes to its standard output before the body.
+// The returned status is taken from the "Status" header, is 302 for redirects via "Location" or zero otherwise.
+func ParseCGIHeader(b []byte) (hdr http.Header, status int, err error) {
+	hdr = http.Header{}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, 0, fmt.Errorf("invalid CGI header line: %q", line)
+		}
+
+		hdr.Add(textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(value))
+	}
+
+	if s := hdr.Get("Status"); s != "" {
+		code, _, _ := strings.Cut(s, " ")
+		if status, err = strconv.Atoi(code); err != nil || status < 100 || status > 999 {
+			return nil, 0, fmt.Errorf("invalid CGI status: %q", s)
+		}
+
+		hdr.Del("Status")
+	} else if hdr.Get("Location") != "" {
+		status = http.StatusFound
+	}
+
+	return hdr, status, nil
+}
+
+// cgiWriter separates the header lines from the body written by a CGI program.
+type cgiWriter struct {
+	r      *Request
+	body   io.Writer
+	stream bool
+
+	buf    bytes.Buffer
+	parsed bool
+	status int
+}
+
+func (w *cgiWriter) Write(p []byte) (int, error) {
+	if w.parsed {
+		return w.body.Write(p)
+	}
+
+	w.buf.Write(p)
+
+	end, rest := cgiHeaderEnd(w.buf.Bytes())
+	if end < 0 {
+		if w.buf.Len() > maxCGIHeaderBytes {
+			return 0, fmt.Errorf("CGI response header exceeds %d bytes", maxCGIHeaderBytes)
+		}
+
+		return len(p), nil
+	}
+
+	hdr, status, err := ParseCGIHeader(w.buf.Bytes()[:end])
+	if err != nil {
+		return 0, err
+	}
+
+	w.parsed = true
+	w.status = status
+	if w.status == 0 {
+		w.status = w.r.result.Status
+	}
+
+	rhdr := w.r.response.Header()
+	for name, values := range hdr {
+		rhdr[name] = values
+	}
+
+	if w.stream {
+		w.r.writeHeader(w.status)
+	}
+
+	if _, err := w.body.Write(w.buf.Bytes()[rest:]); err != nil {
+		return 0, err
+	}
+
+	w.buf.Reset()
+
+	return len(p), nil
+}
+
+// cgiHeaderEnd returns the offsets of the empty line which terminates the header and of the body which follows it.
+func cgiHeaderEnd(b []byte) (end, rest int) {
+	for start := 0; start < len(b); {
+		n := bytes.IndexByte(b[start:], '\n')
+		if n < 0 {
+			break
+		}
+
+		if line := b[start : start+n]; len(line) == 0 || (len(line) == 1 && line[0] == '\r') {
+			return start, start + n + 1
+		}
+
+		start += n + 1
+	}
+
+	return -1, -1
+}
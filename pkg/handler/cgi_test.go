@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler_test
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/handler"
+)
+
+func TestCGIEnvironment(t *testing.T) {
+	req := httptest.NewRequest("POST", "https://example.com:8443/app/cgi-bin/foo?a=1&b=2", strings.NewReader("x=y"))
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.TLS = &tls.ConnectionState{}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "text/plain")
+	req.Header.Add("Cookie", "a=1")
+	req.Header.Add("Cookie", "b=2")
+	req.Header.Set("Proxy", "http://evil.example.com")
+	req.Header["Content_Type"] = []string{"text/plain"}
+	req.Header["X_Forwarded_For"] = []string{"203.0.113.1"}
+
+	t.Setenv("PATH", "/run/current-system/sw/bin")
+
+	env := handler.CGIEnvironment(req, "/app/", 3)
+
+	for _, expected := range []string{
+		"GATEWAY_INTERFACE=CGI/1.1",
+		"SERVER_PROTOCOL=HTTP/1.1",
+		"REQUEST_METHOD=POST",
+		"REQUEST_URI=/app/cgi-bin/foo?a=1&b=2",
+		"QUERY_STRING=a=1&b=2",
+		"SCRIPT_NAME=/app",
+		"PATH_INFO=/cgi-bin/foo",
+		"SERVER_NAME=example.com",
+		"SERVER_PORT=8443",
+		"REMOTE_ADDR=192.0.2.1",
+		"REMOTE_PORT=1234",
+		"HTTPS=on",
+		"CONTENT_TYPE=application/x-www-form-urlencoded",
+		"CONTENT_LENGTH=3",
+		"HTTP_ACCEPT=text/html, text/plain",
+		"HTTP_COOKIE=a=1; b=2",
+		"HTTP_HOST=example.com:8443",
+		"PATH=/run/current-system/sw/bin",
+	} {
+		if !slices.Contains(env, expected) {
+			t.Errorf("missing %q in %q", expected, env)
+		}
+	}
+
+	for _, v := range env {
+		if strings.HasPrefix(v, "HTTP_PROXY=") || strings.HasPrefix(v, "HTTP_CONTENT_TYPE=") || strings.HasPrefix(v, "HTTP_X_FORWARDED_FOR=") {
+			t.Errorf("unexpected variable %q", v)
+		}
+	}
+}
+
+func TestCGIEnvironmentWithoutBody(t *testing.T) {
+	req := httptest.NewRequest("GET", "http://example.com/", nil)
+
+	env := handler.CGIEnvironment(req, "", 0)
+
+	for _, v := range env {
+		if strings.HasPrefix(v, "CONTENT_LENGTH=") || strings.HasPrefix(v, "HTTPS=") {
+			t.Errorf("unexpected variable %q", v)
+		}
+	}
+
+	if !slices.Contains(env, "SERVER_PORT=80") || !slices.Contains(env, "PATH_INFO=/") {
+		t.Errorf("unexpected environment %q", env)
+	}
+}
+
+func TestParseCGIHeader(t *testing.T) {
+	hdr, status, err := handler.ParseCGIHeader([]byte("Status: 404 Not Found\r\nContent-Type: text/html\r\nx-custom: a\nX-Custom: b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status != 404 {
+		t.Errorf("status = %d", status)
+	}
+
+	if ct := hdr.Get("Content-Type"); ct != "text/html" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	if v := hdr.Values("X-Custom"); !slices.Equal(v, []string{"a", "b"}) {
+		t.Errorf("X-Custom = %q", v)
+	}
+
+	if hdr.Get("Status") != "" {
+		t.Error("Status must not be passed as header")
+	}
+
+	if _, status, _ := handler.ParseCGIHeader([]byte("Location: /foo\n")); status != 302 {
+		t.Errorf("redirect status = %d", status)
+	}
+
+	if _, status, _ := handler.ParseCGIHeader([]byte("Content-Type: text/plain\n")); status != 0 {
+		t.Errorf("default status = %d", status)
+	}
+
+	for _, invalid := range []string{
+		"no colon\n",
+		"Status: abc\n",
+		"Bad Name: x\n",
+	} {
+		if _, _, err := handler.ParseCGIHeader([]byte(invalid)); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/util"
+)
+
+const (
+	ContentTypeDOT = "text/vnd.graphviz"
+	ContentTypeSVG = "image/svg+xml"
+)
+
+func (r *Request) closure() error {
+	if r.result.Type != options.DerivationType && r.result.Type != options.PathType {
+		return fmt.Errorf("invalid combination of type and mode")
+	}
+
+	if !r.handler.checkPath(r.body) || !strings.HasPrefix(r.body, r.handler.env.StoreDir) {
+		return ForbiddenPathError(r.body)
+	}
+
+	offers := []string{ContentTypeJSON, ContentTypeDOT}
+	if r.handler.opts.DotPath != "" {
+		offers = append(offers, ContentTypeSVG)
+	}
+
+	r.response.Header().Add("Vary", "Accept")
+
+	contentType := util.Negotiate(r.request.Header.Get("Accept"), offers...)
+	if contentType == "" {
+		return r.notAcceptable(offers)
+	}
+
+	infos, err := nix.PathInfos(r.request.Context(), r.handler.opts.Verbose, true, r.body)
+	if err != nil {
+		return fmt.Errorf("failed to get closure: %w", err)
+	}
+
+	c := nix.NewClosure(r.body, infos)
+
+	if r.result.WhyDepends != "" {
+		if c, err = c.WhyDepends(r.result.WhyDepends); err != nil {
+			hdr := r.response.Header()
+			hdr.Set("Content-Type", "text/plain; charset=utf-8")
+
+			r.writeHeader(http.StatusNotFound)
+
+			_, err = fmt.Fprintln(r.response, err)
+
+			return err
+		}
+	}
+
+	var body bytes.Buffer
+
+	switch contentType {
+	case ContentTypeJSON:
+		util.DumpJSONf(&body, c)
+
+	case ContentTypeDOT:
+		if err := c.WriteDOT(&body); err != nil {
+			return err
+		}
+
+	case ContentTypeSVG:
+		svg, err := r.renderSVG(c)
+		if err != nil {
+			return err
+		}
+
+		body.Write(svg)
+	}
+
+	if body.Len() > int(r.handler.opts.MaxResponseBytes) {
+		return fmt.Errorf("response body exceeds maximum size: %d > %d Bytes", body.Len(), r.handler.opts.MaxResponseBytes)
+	}
+
+	if contentType != ContentTypeSVG {
+		contentType += "; charset=utf-8"
+	}
+
+	r.response.Header().Set("Content-Type", contentType)
+
+	r.writeHeader(0) // WriteHeader() is called by ServeContent()
+	http.ServeContent(r.response, r.request, "", time.Time{}, bytes.NewReader(body.Bytes()))
+
+	return nil
+}
+
+// renderSVG renders the reference graph of the closure with the configured GraphViz binary.
+func (r *Request) renderSVG(c *nix.Closure) ([]byte, error) {
+	var dot bytes.Buffer
+	if err := c.WriteDOT(&dot); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(r.request.Context(), r.handler.opts.MaxRunTime)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, r.handler.opts.DotPath, "-Tsvg")
+
+	svg, _, err := util.Run(cmd, 0, r.handler.opts.Verbose, &dot, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render graph: %w", err)
+	}
+
+	return svg, nil
+}
@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg/util"
+)
+
+// precompressedSiblings maps content codings to the file extension of precompressed siblings in order of preference.
+var precompressedSiblings = []struct {
+	Encoding  string
+	Extension string
+}{
+	{"br", ".br"},
+	{CompressionZstd, ".zst"},
+	{CompressionGzip, ".gz"},
+}
+
+// onTheFlyEncodings are the content codings which can be applied while streaming in order of preference.
+var onTheFlyEncodings = []string{CompressionZstd, CompressionGzip}
+
+var compressibleContentTypes = []string{
+	"application/javascript",
+	"application/json",
+	"application/manifest+json",
+	"application/wasm",
+	"application/xhtml+xml",
+	"application/xml",
+	"image/svg+xml",
+}
+
+func isCompressible(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		slices.Contains(compressibleContentTypes, mediaType)
+}
+
+// detectContentType determines the content type of the response like http.ServeContent does.
+// The reader is rewound afterwards.
+func detectContentType(name string, rd io.ReadSeeker) (string, error) {
+	if ct := mime.TypeByExtension(filepath.Ext(name)); ct != "" {
+		return ct, nil
+	}
+
+	var buf [512]byte
+	n, _ := io.ReadFull(rd, buf[:])
+
+	if _, err := rd.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// precompressed returns an accepted precompressed sibling of the file at path.
+func (r *Request) precompressed(path string) (sibling, encoding string) {
+	acceptEncoding := r.request.Header.Get("Accept-Encoding")
+	if acceptEncoding == "" {
+		return "", ""
+	}
+
+	available := map[string]string{}
+	encodings := []string{}
+
+	for _, pc := range precompressedSiblings {
+		// Siblings are checked like the file itself after resolving symlinks
+		sibling, err := filepath.EvalSymlinks(path + pc.Extension)
+		if err != nil || !r.handler.checkPath(sibling) {
+			continue
+		}
+
+		if fi, err := os.Stat(sibling); err != nil || !fi.Mode().IsRegular() || fi.Size() > r.handler.opts.MaxResponseBytes {
+			continue
+		}
+
+		available[pc.Encoding] = sibling
+		encodings = append(encodings, pc.Encoding)
+	}
+
+	if encoding = util.Negotiate(acceptEncoding, encodings...); encoding == "" {
+		return "", ""
+	}
+
+	return available[encoding], encoding
+}
+
+// compressOnTheFly returns the content coding which should be applied while streaming the response.
+// Range requests are answered uncompressed as offsets refer to the selected representation.
+func (r *Request) compressOnTheFly(contentType string, size int64) string {
+	minBytes := r.handler.opts.CompressMinBytes
+	if minBytes < 0 || size < minBytes {
+		return ""
+	}
+
+	if r.request.Header.Get("Range") != "" || r.response.Header().Get("Content-Encoding") != "" {
+		return ""
+	}
+
+	if !isCompressible(contentType) {
+		return ""
+	}
+
+	acceptEncoding := r.request.Header.Get("Accept-Encoding")
+	if acceptEncoding == "" {
+		return ""
+	}
+
+	return util.Negotiate(acceptEncoding, onTheFlyEncodings...)
+}
+
+func (r *Request) serveCompressed(encoding string, rd io.Reader) error {
+	hdr := r.response.Header()
+	hdr.Set("Content-Encoding", encoding)
+	hdr.Del("Content-Length")
+
+	if etag := hdr.Get("ETag"); etag != "" {
+		etag = encodedETag(etag, encoding)
+		hdr.Set("ETag", etag)
+
+		if inm := r.request.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+			hdr.Del("Content-Type")
+			hdr.Del("Content-Encoding")
+			r.writeHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	status := r.result.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	r.writeHeader(status)
+
+	if r.request.Method == http.MethodHead {
+		return nil
+	}
+
+	slog.Debug("Compressing response", slog.String("encoding", encoding))
+
+	return writeCompressed(r.response, encoding, func(wr io.Writer) error {
+		_, err := io.Copy(wr, rd)
+		return err
+	})
+}
@@ -0,0 +1,54 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/options"
+)
+
+func TestPrecompressed(t *testing.T) {
+	allowed := t.TempDir()
+	forbidden := t.TempDir()
+
+	for path, content := range map[string]string{
+		filepath.Join(allowed, "app.js"):      "plain",
+		filepath.Join(allowed, "app.js.gz"):   "gzip",
+		filepath.Join(allowed, "large.js"):    "plain",
+		filepath.Join(allowed, "large.js.gz"): "gzip with more content than allowed",
+		filepath.Join(forbidden, "secret.br"): "secret",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Symlink(filepath.Join(forbidden, "secret.br"), filepath.Join(allowed, "app.js.br")); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Request{
+		handler: &Handler{
+			opts: options.Options{
+				AllowedPaths:     options.Paths{allowed},
+				MaxResponseBytes: 16,
+			},
+		},
+		request: httptest.NewRequest("GET", "/app.js", nil),
+	}
+	r.request.Header.Set("Accept-Encoding", "br, gzip")
+
+	// The Brotli sibling is preferred, but points outside of the allowed paths
+	if sibling, encoding := r.precompressed(filepath.Join(allowed, "app.js")); sibling != filepath.Join(allowed, "app.js.gz") || encoding != CompressionGzip {
+		t.Errorf("unexpected sibling: %s (%s)", sibling, encoding)
+	}
+
+	if sibling, _ := r.precompressed(filepath.Join(allowed, "large.js")); sibling != "" {
+		t.Errorf("sibling exceeding the maximum response size must not be served: %s", sibling)
+	}
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/util"
+)
+
+var (
+	//go:embed diff.html
+	diffHTML string
+
+	diffTemplate = template.Must(template.New("diff").Funcs(template.FuncMap{
+		"size":     nix.FormatSize,
+		"versions": nix.VersionList,
+		"sizeDelta": func(delta int64) string {
+			if delta < 0 {
+				return "-" + nix.FormatSize(-delta)
+			}
+
+			return "+" + nix.FormatSize(delta)
+		},
+	}).Parse(diffHTML))
+)
+
+func (r *Request) diff() error {
+	if r.result.Type != options.DerivationType && r.result.Type != options.PathType {
+		return fmt.Errorf("invalid combination of type and mode")
+	}
+
+	if r.result.DiffBase == "" {
+		return fmt.Errorf("missing base for closure diff")
+	}
+
+	offers := []string{ContentTypeJSON, "text/html"}
+
+	r.response.Header().Add("Vary", "Accept")
+
+	contentType := util.Negotiate(r.request.Header.Get("Accept"), offers...)
+	if contentType == "" {
+		return r.notAcceptable(offers)
+	}
+
+	base := r.result.DiffBase
+	if strings.HasSuffix(base, ".drv") {
+		ctx, cancel := context.WithTimeout(r.request.Context(), r.handler.opts.MaxBuildTime)
+		defer cancel()
+
+		slog.Debug("Building base of closure diff", slog.String("derivation", base))
+
+		paths, err := nix.BuildOutputs(ctx, base, []string{r.result.output()}, false, r.handler.opts.Verbose, nil, nix.FilterOptions(r.handler.opts.NixArgs)...)
+		if err != nil {
+			return fmt.Errorf("failed to build base: %w", err)
+		}
+
+		var ok bool
+		if base, ok = paths[r.result.output()]; !ok {
+			return fmt.Errorf("output '%s' of base has not been built", r.result.output())
+		}
+	}
+
+	closures := []*nix.Closure{}
+	for _, path := range []string{base, r.body} {
+		if !r.handler.checkPath(path) || !strings.HasPrefix(path, r.handler.env.StoreDir) {
+			return ForbiddenPathError(path)
+		}
+
+		infos, err := nix.PathInfos(r.request.Context(), r.handler.opts.Verbose, true, path)
+		if err != nil {
+			return fmt.Errorf("failed to get closure: %w", err)
+		}
+
+		closures = append(closures, nix.NewClosure(path, infos))
+	}
+
+	d := nix.DiffClosures(closures[0], closures[1])
+
+	var body bytes.Buffer
+
+	if contentType == "text/html" {
+		if err := diffTemplate.Execute(&body, d); err != nil {
+			return fmt.Errorf("failed to render diff: %w", err)
+		}
+	} else {
+		util.DumpJSONf(&body, d)
+	}
+
+	if body.Len() > int(r.handler.opts.MaxResponseBytes) {
+		return fmt.Errorf("response body exceeds maximum size: %d > %d Bytes", body.Len(), r.handler.opts.MaxResponseBytes)
+	}
+
+	r.response.Header().Set("Content-Type", contentType+"; charset=utf-8")
+
+	r.writeHeader(0) // WriteHeader() is called by ServeContent()
+	http.ServeContent(r.response, r.request, "", time.Time{}, bytes.NewReader(body.Bytes()))
+
+	return nil
+}
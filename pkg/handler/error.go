@@ -11,9 +11,19 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/seccomp"
+	"github.com/stv0g/nixpresso/pkg/secrets"
 	"github.com/stv0g/nixpresso/pkg/util"
 )
 
+const (
+	ReasonOOM      = "oom"
+	ReasonCPUTime  = "cpuTime"
+	ReasonFileSize = "fileSize"
+	ReasonSeccomp  = "seccomp"
+)
+
 type Error struct {
 	Error error `json:"error,omitempty"`
 
@@ -30,6 +40,11 @@ type Error struct {
 	TermSignal int           `json:"termSignal,omitempty"`
 	StopSignal int           `json:"stopSignal,omitempty"`
 	CoreDump   bool          `json:"coreDump,omitempty"`
+	OOMKills   int           `json:"oomKills,omitempty"`
+
+	// Reason of a termination enforced by the limits: "oom", "cpuTime", "fileSize" or "seccomp"
+	Reason          string   `json:"reason,omitempty"`
+	SeccompProfiles []string `json:"seccompProfiles,omitempty"`
 
 	Stdout string `json:"stdout,omitempty"`
 	Stderr string `json:"stderr,omitempty"`
@@ -40,6 +55,17 @@ func NewError(err error) (e *Error) {
 		Error: err,
 	}
 
+	var oe *limits.OOMError
+	if ok := errors.As(err, &oe); ok {
+		e.OOMKills = oe.Kills
+		e.Reason = ReasonOOM
+	}
+
+	var ve *seccomp.ViolationError
+	if ok := errors.As(err, &ve); ok {
+		e.SeccompProfiles = ve.Profiles
+	}
+
 	var re *util.RunError
 	if ok := errors.As(err, &re); ok {
 		e.Status = http.StatusInternalServerError
@@ -63,6 +89,17 @@ func NewError(err error) (e *Error) {
 					e.ExitStatus = ss.ExitStatus()
 				case ss.Signaled():
 					e.TermSignal = int(ss.Signal())
+
+					switch ss.Signal() {
+					case syscall.SIGXCPU:
+						e.Reason = ReasonCPUTime
+					case syscall.SIGXFSZ:
+						e.Reason = ReasonFileSize
+					case syscall.SIGSYS:
+						if ve != nil {
+							e.Reason = ReasonSeccomp
+						}
+					}
 				case ss.Stopped():
 					e.StopSignal = int(ss.StopSignal())
 				}
@@ -75,6 +112,22 @@ func NewError(err error) (e *Error) {
 	return e
 }
 
+// Redact replaces the values of secrets in the error and the output of the program.
+func (e *Error) Redact(r *secrets.Redactor) *Error {
+	if e.Error != nil {
+		if msg := e.Error.Error(); r.Redact(msg) != msg {
+			e.Error = errors.New(r.Redact(msg))
+		}
+	}
+
+	e.Args = r.RedactAll(e.Args)
+	e.Env = r.RedactAll(e.Env)
+	e.Stdout = r.Redact(e.Stdout)
+	e.Stderr = r.Redact(e.Stderr)
+
+	return e
+}
+
 func Errorf(status int, format string, args ...any) *Error {
 	return &Error{
 		Status: status,
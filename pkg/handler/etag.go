@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+)
+
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// StoreETag derives a strong entity tag for a file in the Nix store from the hash of its store path and its sub-path.
+// Files in the store never change, so the tag stays valid for as long as the store path exists.
+// An empty string is returned for paths outside of the store.
+func StoreETag(storeDir, path string) string {
+	rel, ok := strings.CutPrefix(path, storeDir+"/")
+	if !ok {
+		return ""
+	}
+
+	name, subPath, _ := strings.Cut(rel, "/")
+
+	hash := nix.HashPart(name)
+	if hash == "" || hash == name {
+		return ""
+	}
+
+	if subPath == "" {
+		return fmt.Sprintf(`"%s"`, hash)
+	}
+
+	sum := sha256.Sum256([]byte(subPath))
+
+	return fmt.Sprintf(`"%s-%s"`, hash, nix.EncodeNix32(sum[:20]))
+}
+
+// setStoreETag sets the entity tag of a response serving a file from the Nix store.
+// ETags provided by the handler take precedence.
+// It enables http.ServeContent to answer "If-None-Match" and "If-Range" headers.
+func (r *Request) setStoreETag(path string) {
+	hdr := r.response.Header()
+	if hdr.Get("ETag") != "" {
+		return
+	}
+
+	if etag := StoreETag(r.handler.env.StoreDir, path); etag != "" {
+		hdr.Set("ETag", etag)
+	}
+}
+
+// encodedETag derives the entity tag of a representation with a content coding applied on-the-fly.
+func encodedETag(etag, encoding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+
+	return strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+}
+
+// etagMatches checks if the entity tag is contained in an "If-None-Match" header using weak comparison.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,41 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler_test
+
+import (
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/handler"
+)
+
+func TestStoreETag(t *testing.T) {
+	const storeDir = "/nix/store"
+	const storePath = storeDir + "/0c1g3xbmd6jyqcmlqdcjdhd6r9jzymbp-hello-2.12.1"
+
+	root := handler.StoreETag(storeDir, storePath)
+	if root != `"0c1g3xbmd6jyqcmlqdcjdhd6r9jzymbp"` {
+		t.Errorf("StoreETag() = %s", root)
+	}
+
+	bin := handler.StoreETag(storeDir, storePath+"/bin/hello")
+	share := handler.StoreETag(storeDir, storePath+"/share/hello")
+
+	if bin == root || bin == share {
+		t.Errorf("StoreETag() must differ between sub-paths: %s, %s, %s", root, bin, share)
+	}
+
+	if bin != handler.StoreETag(storeDir, storePath+"/bin/hello") {
+		t.Error("StoreETag() must be deterministic")
+	}
+
+	for _, path := range []string{
+		"/var/www/index.html",
+		storeDir,
+		storeDir + "/invalid",
+	} {
+		if etag := handler.StoreETag(storeDir, path); etag != "" {
+			t.Errorf("StoreETag(%s) = %s, want empty", path, etag)
+		}
+	}
+}
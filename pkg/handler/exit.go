@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// Trailers which carry the exit status of programs whose output is streamed.
+	TrailerExitStatus = "X-Exit-Status"
+	TrailerExitSignal = "X-Exit-Signal"
+
+	// ExitStatusOther matches all unsuccessful exits which are not mapped explicitly.
+	ExitStatusOther = "*"
+)
+
+// ExitStatus returns the exit code or the name of the signal which terminated a program.
+// It returns false if the error was not caused by an exited program.
+func ExitStatus(err error) (code int, signal string, ok bool) {
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return 0, "", false
+	}
+
+	if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return -1, unix.SignalName(ws.Signal()), true
+	}
+
+	return ee.ExitCode(), "", true
+}
+
+// MapExitStatus returns the HTTP status which the handler has assigned to an exit code or signal.
+// Keys of the mapping are exit codes, signal names like "SIGKILL" or "*" for all other unsuccessful exits.
+func MapExitStatus(mapping map[string]int, code int, signal string) (int, bool) {
+	key := signal
+	if key == "" {
+		key = strconv.Itoa(code)
+	}
+
+	if status, ok := mapping[key]; ok {
+		return status, true
+	}
+
+	if code != 0 || signal != "" {
+		if status, ok := mapping[ExitStatusOther]; ok {
+			return status, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler_test
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/handler"
+)
+
+func TestExitStatus(t *testing.T) {
+	err := exec.Command("/bin/sh", "-c", "exit 3").Run()
+	if code, signal, ok := handler.ExitStatus(err); !ok || code != 3 || signal != "" {
+		t.Errorf("ExitStatus() = %d, %q, %t", code, signal, ok)
+	}
+
+	err = exec.Command("/bin/sh", "-c", "kill -KILL $$").Run()
+	if code, signal, ok := handler.ExitStatus(err); !ok || code != -1 || signal != "SIGKILL" {
+		t.Errorf("ExitStatus() = %d, %q, %t", code, signal, ok)
+	}
+
+	if _, _, ok := handler.ExitStatus(errors.New("other")); ok {
+		t.Error("ExitStatus() must not match other errors")
+	}
+}
+
+func TestMapExitStatus(t *testing.T) {
+	mapping := map[string]int{
+		"0":       201,
+		"2":       400,
+		"3":       404,
+		"SIGKILL": 504,
+	}
+
+	for _, tc := range []struct {
+		code   int
+		signal string
+		status int
+		ok     bool
+	}{
+		{0, "", 201, true},
+		{2, "", 400, true},
+		{3, "", 404, true},
+		{-1, "SIGKILL", 504, true},
+		{4, "", 0, false},
+		{-1, "SIGTERM", 0, false},
+	} {
+		if status, ok := handler.MapExitStatus(mapping, tc.code, tc.signal); status != tc.status || ok != tc.ok {
+			t.Errorf("MapExitStatus(%d, %q) = %d, %t", tc.code, tc.signal, status, ok)
+		}
+	}
+
+	mapping = map[string]int{"*": 500}
+
+	if _, ok := handler.MapExitStatus(mapping, 0, ""); ok {
+		t.Error("successful exits must not match the fallback")
+	}
+
+	if status, ok := handler.MapExitStatus(mapping, 1, ""); !ok || status != 500 {
+		t.Errorf("MapExitStatus() = %d, %t", status, ok)
+	}
+
+	if status, ok := handler.MapExitStatus(mapping, -1, "SIGTERM"); !ok || status != 500 {
+		t.Errorf("MapExitStatus() = %d, %t", status, ok)
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/gateway"
+)
+
+// runGateway passes the request to a persistent FastCGI or SCGI application instead of starting a process per request.
+// FastCGI applications receive their listening socket via standard input and can share it between multiple workers.
+func (r *Request) runGateway(ctx context.Context, root string, argv []string, stdin io.Reader, contentLength int64, stdout, stderr io.Writer) (int, error) {
+	if r.result.PTY {
+		return 0, fmt.Errorf("PTYs are not supported with the %s protocol", r.result.Protocol)
+	}
+
+	spec := r.serviceSpec(root)
+	spec.Args = argv
+	spec.ListenStdin = r.result.Protocol == gateway.ProtocolFastCGI
+	spec.Workers = r.result.Workers
+
+	inst, release, err := r.acquireService(ctx, spec)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	conn, err := inst.Dial(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to connect to service '%s': %w", spec.Name, err)
+	}
+	defer conn.Close() //nolint:errcheck
+
+	// Abort the request when the run timeout expires or the client disconnects.
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close() //nolint:errcheck
+	})
+	defer stop()
+
+	params := CGIEnvironment(r.request, r.handler.opts.BasePath, contentLength)
+	params = append(params, spec.Env...)
+
+	// Applications outlive the request, so they receive the secrets with each request instead of their environment.
+	secretsEnv, secretsDir, err := r.secrets(nil)
+	if secretsDir != "" {
+		defer os.RemoveAll(secretsDir) //nolint:errcheck
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	params = append(params, secretsEnv...)
+
+	slog.Debug("Passing request to service",
+		slog.String("service", spec.Name),
+		slog.String("protocol", r.result.Protocol),
+		slog.Int("pid", inst.PID()))
+
+	// The gateway interrupts reading the remaining request body once the application has responded.
+	if stdin == io.Reader(r.request.Body) {
+		stdin = &interruptibleBody{
+			ReadCloser: r.request.Body,
+			rc:         http.NewResponseController(r.response),
+		}
+	}
+
+	switch r.result.Protocol {
+	case gateway.ProtocolFastCGI:
+		return gateway.FastCGI(conn, params, stdin, stdout, stderr)
+
+	case gateway.ProtocolSCGI:
+		return 0, gateway.SCGI(conn, params, stdin, contentLength, r.handler.opts.MaxRequestBytes, stdout)
+	}
+
+	return 0, fmt.Errorf("invalid protocol: %s", r.result.Protocol)
+}
+
+// interruptibleBody unblocks pending reads of the request body when it is closed.
+// Closing the body of HTTP/1.x requests would otherwise wait for the pending read.
+type interruptibleBody struct {
+	io.ReadCloser
+	rc *http.ResponseController
+}
+
+func (b *interruptibleBody) Close() error {
+	b.rc.SetReadDeadline(time.Now()) //nolint:errcheck
+
+	return b.ReadCloser.Close()
+}
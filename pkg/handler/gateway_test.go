@@ -0,0 +1,102 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/fcgi"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/gateway"
+	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/proxy"
+	"github.com/stv0g/nixpresso/pkg/secrets"
+)
+
+// The test binary itself acts as the FastCGI application.
+func TestMain(m *testing.M) {
+	if os.Getenv("TEST_FASTCGI_SERVICE") != "" {
+		serveFastCGI()
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+func serveFastCGI() {
+	l, err := net.FileListener(os.Stdin)
+	if err != nil {
+		panic(err)
+	}
+
+	fcgi.Serve(l, http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) { //nolint:errcheck
+		env := fcgi.ProcessEnv(req)
+
+		secretFile, _ := os.ReadFile(filepath.Join(env[EnvCredentialsDirectory], "certificate"))
+
+		fmt.Fprintf(wr, "%s %s", env["API_TOKEN"], secretFile)
+	}))
+}
+
+func TestRunGatewaySecrets(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, value := range map[string]string{"token": "s3cr3t", "certificate": "cert"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := secrets.NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supervisor := proxy.NewSupervisor(0, "")
+	defer supervisor.Close() //nolint:errcheck
+
+	rec := httptest.NewRecorder()
+	r := &Request{
+		handler: &Handler{
+			opts: options.Options{
+				AllowedPaths: options.Paths{filepath.Dir(exe) + "/"},
+				MaxRunTime:   10 * time.Second,
+			},
+			secrets:    store,
+			supervisor: supervisor,
+		},
+		request:  httptest.NewRequest("GET", "/", nil),
+		response: rec,
+		timings:  map[string]time.Duration{},
+		body:     exe,
+		result: &EvalResult{
+			Type:        options.PathType,
+			Mode:        options.RunMode,
+			Status:      200,
+			Protocol:    gateway.ProtocolFastCGI,
+			Service:     "test",
+			Env:         map[string]string{"TEST_FASTCGI_SERVICE": "1"},
+			Secrets:     map[string]string{"API_TOKEN": "token"},
+			SecretFiles: []string{"certificate"},
+		},
+	}
+
+	if err := r.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if body := rec.Body.String(); body != "s3cr3t cert" {
+		t.Errorf("Unexpected response: %d %q", rec.Code, body)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -17,6 +18,10 @@ import (
 	"github.com/stv0g/nixpresso/pkg/cache"
 	"github.com/stv0g/nixpresso/pkg/nix"
 	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/proxy"
+	"github.com/stv0g/nixpresso/pkg/seccomp"
+	"github.com/stv0g/nixpresso/pkg/secrets"
+	"github.com/stv0g/nixpresso/pkg/users"
 	"github.com/stv0g/nixpresso/pkg/util"
 )
 
@@ -32,10 +37,22 @@ type Handler struct {
 	FlakeReference string
 	FlakeStorePath string
 
-	cache *cache.MemoryCache[cache.NamedStringKey, *EvalResult]
+	cache    *cache.MemoryCache[cache.NamedStringKey, *EvalResult]
+	cacheKey *nix.SecretKey
+
+	supervisor *proxy.Supervisor
+
+	seccompProfiles map[string]*seccomp.Profile
+
+	users   *users.Pool
+	secrets *secrets.Store
 }
 
 func NewHandler(opts options.Options) (h *Handler, err error) {
+	if err := validateOptions(opts); err != nil {
+		return nil, err
+	}
+
 	h = &Handler{
 		opts: opts,
 	}
@@ -94,15 +111,81 @@ func NewHandler(opts options.Options) (h *Handler, err error) {
 		}
 	}
 
+	if h.opts.CacheSecretKeyFile != "" {
+		key, err := os.ReadFile(h.opts.CacheSecretKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read binary cache secret key: %w", err)
+		}
+
+		if h.cacheKey, err = nix.ParseSecretKey(string(key)); err != nil {
+			return nil, fmt.Errorf("failed to parse binary cache secret key: %w", err)
+		}
+	}
+
 	if h.InspectResult.Pure && h.opts.EvalCache {
 		if h.cache, err = cache.NewMemoryCache[cache.NamedStringKey, *EvalResult](16 << 10); err != nil {
 			return nil, fmt.Errorf("failed to create cache: %w", err)
 		}
 	}
 
+	if h.seccompProfiles, err = seccomp.LoadProfiles(h.opts.SeccompProfiles); err != nil {
+		return nil, err
+	}
+
+	if name := h.opts.SeccompProfile; name != "" && name != seccomp.NoProfileName && h.seccompProfiles[name] == nil {
+		return nil, fmt.Errorf("unknown seccomp profile: %s", name)
+	}
+
+	h.users = users.NewPool(h.opts.RunUsers)
+
+	if h.secrets, err = secrets.NewStore(h.opts.SecretsDir); err != nil {
+		return nil, err
+	}
+
+	if slices.Contains(h.opts.AllowedModes, options.ProxyMode) || slices.Contains(h.opts.AllowedModes, options.RunMode) {
+		h.supervisor = proxy.NewSupervisor(h.opts.ProxyIdleTimeout, h.opts.Cgroup)
+	}
+
 	return h, nil
 }
 
+// validateOptions rejects combinations of options which can not be enforced.
+func validateOptions(opts options.Options) error {
+	// Quotas are enforced by cgroups only.
+	if opts.Cgroup == "" {
+		switch {
+		case opts.Limits.Memory > 0:
+			return fmt.Errorf("--limit-memory requires --cgroup")
+		case opts.Limits.CPU > 0:
+			return fmt.Errorf("--limit-cpu requires --cgroup")
+		case opts.Limits.Processes > 0:
+			return fmt.Errorf("--limit-processes requires --cgroup")
+		}
+	}
+
+	// Services outlive a single request, so they can neither be sandboxed nor run as dedicated users.
+	if slices.Contains(opts.AllowedModes, options.ProxyMode) {
+		if opts.Sandbox {
+			return fmt.Errorf("the %s mode can not be allowed together with --sandbox", options.ProxyMode)
+		}
+
+		if opts.RunUsers.Size() > 0 {
+			return fmt.Errorf("the %s mode can not be allowed together with --run-users", options.ProxyMode)
+		}
+	}
+
+	return nil
+}
+
+// Close stops all services started by the proxy mode or for FastCGI and SCGI applications.
+func (h *Handler) Close() error {
+	if h.supervisor == nil {
+		return nil
+	}
+
+	return h.supervisor.Close()
+}
+
 func (h *Handler) ListenAndServe(addr string, rdTo, wrTo time.Duration, tlsCertFilename, tlsKeyFilename string) (err error) {
 	s := &http.Server{
 		Addr:                         addr,
@@ -127,6 +210,11 @@ func (h *Handler) ListenAndServe(addr string, rdTo, wrTo time.Duration, tlsCertF
 }
 
 func (h *Handler) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
+	if h.opts.OpenAPIPath != "" && req.URL.Path == h.opts.OpenAPIPath {
+		h.serveOpenAPI(wr, req)
+		return
+	}
+
 	r := &Request{
 		request:  req,
 		handler:  h,
@@ -160,6 +248,38 @@ func (h *Handler) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// rootRoute describes a handler which does not use "lib.handlers.router".
+func (h *Handler) rootRoute() *Route {
+	return &Route{
+		Path:         "/",
+		Match:        RouteMatchPrefix,
+		Description:  h.InspectResult.Description,
+		Methods:      h.InspectResult.Methods,
+		ExpectedArgs: h.InspectResult.ExpectedArgs,
+		Schema:       h.InspectResult.Schema,
+	}
+}
+
+// route returns the route of the handler which matches the request.
+func (h *Handler) route(req *http.Request) *Route {
+	if len(h.InspectResult.Routes) == 0 {
+		return h.rootRoute()
+	}
+
+	path := strings.TrimPrefix(req.URL.Path, h.opts.BasePath)
+
+	return matchRoute(h.InspectResult.Routes, path)
+}
+
+// Routes returns the flattened route table of the handler.
+func (h *Handler) Routes() []Route {
+	if len(h.InspectResult.Routes) == 0 {
+		return []Route{*h.rootRoute()}
+	}
+
+	return flattenRoutes(h.InspectResult.Routes)
+}
+
 func (h *Handler) checkPath(path string) bool {
 	if util.ContainsDotDot(path) {
 		return false
@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/users"
+)
+
+func TestValidateOptions(t *testing.T) {
+	proxyMode := []string{options.ServeMode, options.ProxyMode}
+
+	for _, tc := range []struct {
+		name  string
+		opts  options.Options
+		valid bool
+	}{
+		{"default", options.Options{}, true},
+		{"proxy", options.Options{AllowedModes: proxyMode}, true},
+		{"sandbox", options.Options{Sandbox: true}, true},
+		{"proxy with sandbox", options.Options{AllowedModes: proxyMode, Sandbox: true}, false},
+		{"memory with cgroup", options.Options{Cgroup: "/sys/fs/cgroup/nixpresso", Limits: limits.Limits{Memory: 1 << 30}}, true},
+		{"memory without cgroup", options.Options{Limits: limits.Limits{Memory: 1 << 30}}, false},
+		{"cpu without cgroup", options.Options{Limits: limits.Limits{CPU: 0.5}}, false},
+		{"processes without cgroup", options.Options{Limits: limits.Limits{Processes: 16}}, false},
+		{"rlimits without cgroup", options.Options{Limits: limits.Limits{OpenFiles: 64}}, true},
+		{"proxy with users", options.Options{AllowedModes: proxyMode, RunUsers: users.Range{First: 1000, Last: 1009}}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateOptions(tc.opts); (err == nil) != tc.valid {
+				t.Errorf("Unexpected result: %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteErrorMaxBytes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := &Request{
+		response: rec,
+	}
+
+	r.writeError(fmt.Errorf("failed to read request body: %w", &http.MaxBytesError{Limit: 1024}))
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Unexpected status: %d", rec.Code)
+	}
+}
@@ -32,10 +32,15 @@ type InspectResult struct {
 	EvalCacheIgnore EvalCacheIgnore `json:"evalCacheIgnore,omitempty"`
 
 	ExpectedArgs map[string]bool `json:"expectedArgs,omitempty"`
+	Schema       *RequestSchema  `json:"schema,omitempty"`
+	Methods      []string        `json:"methods,omitempty"`
+	Routes       []Route         `json:"routes,omitempty"`
 	Pure         bool            `json:"pure,omitempty"`
 
 	EvalArgs []string `json:"evalArgs,omitempty"`
 	PTY      bool     `json:"pty,omitempty"`
+
+	DeferBody bool `json:"deferBody,omitempty"`
 }
 
 func (h *Handler) inspect() (err error) {
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg/listing"
+	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/util"
+)
+
+const (
+	listingDefaultLimit = 100
+	listingMaxLimit     = 1000
+)
+
+var (
+	//go:embed listing.html
+	listingHTML string
+
+	listingTemplate = template.Must(template.New("listing").Funcs(template.FuncMap{
+		"add": func(a, b int) int { return a + b },
+		"sub": func(a, b int) int { return a - b },
+		"href": func(e listing.Entry) string {
+			u := &url.URL{Path: e.Name}
+			if e.Type == listing.TypeDirectory {
+				u.Path += "/"
+			}
+
+			return u.String()
+		},
+	}).Parse(listingHTML))
+)
+
+type Listing struct {
+	Path    string          `json:"path"`
+	Entries []listing.Entry `json:"entries"`
+	Total   int             `json:"total"`
+	Page    int             `json:"page"`
+	Pages   int             `json:"pages"`
+	Limit   int             `json:"limit"`
+
+	query url.Values
+}
+
+func (l *Listing) SortQuery(key string) template.URL {
+	q := maps.Clone(l.query)
+	q.Del("page")
+	q.Set("sort", key)
+
+	if l.query.Get("sort") == key && l.query.Get("order") != "desc" {
+		q.Set("order", "desc")
+	} else {
+		q.Del("order")
+	}
+
+	return template.URL("?" + q.Encode()) //nolint:gosec
+}
+
+func (l *Listing) PageQuery(page int) template.URL {
+	q := maps.Clone(l.query)
+	q.Set("page", strconv.Itoa(page))
+
+	return template.URL("?" + q.Encode()) //nolint:gosec
+}
+
+func (r *Request) listing() (err error) {
+	if r.result.Type != options.DerivationType && r.result.Type != options.PathType {
+		return fmt.Errorf("invalid combination of type and mode")
+	}
+
+	r.body = filepath.Join(r.body, r.result.SubPath)
+
+	if r.body, err = filepath.EvalSymlinks(r.body); err != nil {
+		return fmt.Errorf("failed to evaluate symlink '%s': %w", r.body, err)
+	}
+
+	if !r.handler.checkPath(r.body) {
+		return ForbiddenPathError(r.body)
+	}
+
+	if fi, err := os.Stat(r.body); err != nil {
+		return fmt.Errorf("failed to stat '%s': %w", r.body, err)
+	} else if !fi.IsDir() {
+		return fmt.Errorf("not a directory: %s", r.body)
+	}
+
+	offers := []string{"application/json", "text/html"}
+
+	contentType := util.Negotiate(r.request.Header.Get("Accept"), offers...)
+	if contentType == "" {
+		return r.notAcceptable(offers)
+	}
+
+	// Relative links in the HTML page require a trailing slash
+	if contentType == "text/html" && !strings.HasSuffix(r.request.URL.Path, "/") {
+		u := *r.request.URL
+		u.Path += "/"
+
+		r.writeHeader(0)
+		http.Redirect(r.response, r.request, u.String(), http.StatusMovedPermanently)
+		return nil
+	}
+
+	query := r.request.URL.Query()
+	ve := &ValidationError{}
+
+	page, limit := 1, listingDefaultLimit
+	if s := query.Get("page"); s != "" {
+		if page, err = strconv.Atoi(s); err != nil || page < 1 {
+			ve.add("query.page", "must be a positive integer")
+		}
+	}
+
+	if s := query.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 || limit > listingMaxLimit {
+			ve.add("query.limit", "must be an integer between 1 and %d", listingMaxLimit)
+		}
+	}
+
+	order := query.Get("order")
+	if order != "" && order != "asc" && order != "desc" {
+		ve.add("query.order", "must be one of [asc desc]")
+	}
+
+	entries, err := listing.Read(r.body, r.handler.checkPath)
+	if err != nil {
+		return err
+	}
+
+	if err := listing.Sort(entries, query.Get("sort"), order == "desc"); err != nil {
+		ve.add("query.sort", "must be one of %v", listing.AllSortKeys)
+	}
+
+	if len(ve.Issues) > 0 {
+		return ve
+	}
+
+	l := &Listing{
+		Path:    r.request.URL.Path,
+		Entries: listing.Page(entries, page, limit),
+		Total:   len(entries),
+		Page:    page,
+		Pages:   max(1, (len(entries)+limit-1)/limit),
+		Limit:   limit,
+		query:   query,
+	}
+
+	hdr := r.response.Header()
+	hdr.Add("Vary", "Accept")
+	if hdr.Get("Content-Type") == "" {
+		hdr.Set("Content-Type", contentType+"; charset=utf-8")
+	}
+
+	r.writeHeader(r.result.Status)
+
+	if r.request.Method == http.MethodHead {
+		return nil
+	}
+
+	if contentType == "text/html" {
+		return listingTemplate.Execute(r.response, l)
+	}
+
+	util.DumpJSONf(r.response, l)
+
+	return nil
+}
@@ -0,0 +1,307 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp/syntax"
+	"slices"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg"
+	"github.com/stv0g/nixpresso/pkg/util"
+)
+
+const OpenAPIVersion = "3.0.3"
+
+type OpenAPI struct {
+	OpenAPI string                     `json:"openapi"`
+	Info    OpenAPIInfo                `json:"info"`
+	Paths   map[string]OpenAPIPathItem `json:"paths"`
+}
+
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// OpenAPIPathItem maps lower-case HTTP methods to operations.
+type OpenAPIPathItem map[string]*OpenAPIOperation
+
+type OpenAPIOperation struct {
+	Description string                     `json:"description,omitempty"`
+	Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]OpenAPIResponse `json:"responses"`
+
+	Match string `json:"x-nixpresso-match,omitempty"`
+}
+
+type OpenAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description,omitempty"`
+	Required    bool           `json:"required,omitempty"`
+	Schema      *OpenAPISchema `json:"schema,omitempty"`
+}
+
+type OpenAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]OpenAPIMediaType `json:"content"`
+}
+
+type OpenAPIMediaType struct {
+	Schema *OpenAPISchema `json:"schema,omitempty"`
+}
+
+type OpenAPIResponse struct {
+	Description string `json:"description"`
+}
+
+type OpenAPISchema struct {
+	Type        string                    `json:"type,omitempty"`
+	Description string                    `json:"description,omitempty"`
+	Enum        []any                     `json:"enum,omitempty"`
+	MinLength   *int                      `json:"minLength,omitempty"`
+	MaxLength   *int                      `json:"maxLength,omitempty"`
+	MinItems    *int                      `json:"minItems,omitempty"`
+	MaxItems    *int                      `json:"maxItems,omitempty"`
+	Minimum     *float64                  `json:"minimum,omitempty"`
+	Maximum     *float64                  `json:"maximum,omitempty"`
+	Pattern     string                    `json:"pattern,omitempty"`
+	Properties  map[string]*OpenAPISchema `json:"properties,omitempty"`
+	Required    []string                  `json:"required,omitempty"`
+	Items       *OpenAPISchema            `json:"items,omitempty"`
+}
+
+// OpenAPI builds an OpenAPI document from the routes, methods, schemas and descriptions declared by the handler.
+func (h *Handler) OpenAPI() *OpenAPI {
+	doc := &OpenAPI{
+		OpenAPI: OpenAPIVersion,
+		Info: OpenAPIInfo{
+			Title:       "Nixpresso",
+			Description: h.InspectResult.Description,
+			Version:     pkg.Version,
+		},
+		Paths: map[string]OpenAPIPathItem{},
+	}
+
+	for _, route := range h.Routes() {
+		path := h.opts.BasePath + route.Path
+
+		var pathParams []OpenAPIParameter
+		if route.Match == RouteMatchRegex {
+			path, pathParams = openAPIPath(route.Path)
+			path = h.opts.BasePath + path
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = OpenAPIPathItem{}
+			doc.Paths[path] = item
+		}
+
+		for _, method := range routeMethods(route) {
+			// The first matching route wins like in the router itself
+			if _, ok := item[method]; ok {
+				continue
+			}
+
+			item[method] = openAPIOperation(route, pathParams)
+		}
+	}
+
+	return doc
+}
+
+func openAPIOperation(route Route, pathParams []OpenAPIParameter) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		Description: route.Description,
+		Parameters:  slices.Clone(pathParams),
+		Responses: map[string]OpenAPIResponse{
+			"default": {Description: "Response of the handler"},
+		},
+		Match: route.Match,
+	}
+
+	if route.Schema == nil {
+		return op
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(route.Schema.Query)) {
+		op.Parameters = append(op.Parameters, openAPIParameter(name, "query", route.Schema.Query[name]))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(route.Schema.Headers)) {
+		op.Parameters = append(op.Parameters, openAPIParameter(name, "header", route.Schema.Headers[name]))
+	}
+
+	if s := route.Schema.Body; s != nil {
+		contentTypes := []string{"application/json"}
+		switch s.Type {
+		case SchemaTypeObject, "":
+			contentTypes = append(contentTypes, "application/x-www-form-urlencoded")
+		case SchemaTypeString:
+			contentTypes = []string{"text/plain"}
+		}
+
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: s.Required,
+			Content:  map[string]OpenAPIMediaType{},
+		}
+
+		for _, contentType := range contentTypes {
+			op.RequestBody.Content[contentType] = OpenAPIMediaType{
+				Schema: s.OpenAPI(),
+			}
+		}
+	}
+
+	return op
+}
+
+func openAPIParameter(name, in string, s *Schema) OpenAPIParameter {
+	return OpenAPIParameter{
+		Name:        name,
+		In:          in,
+		Description: s.Description,
+		Required:    s.Required,
+		Schema:      s.OpenAPI(),
+	}
+}
+
+// OpenAPI converts the schema to an OpenAPI schema object.
+func (s *Schema) OpenAPI() *OpenAPISchema {
+	o := &OpenAPISchema{
+		Type:        s.Type,
+		Description: s.Description,
+		Enum:        s.Enum,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		Pattern:     s.Pattern,
+	}
+
+	if s.Type == SchemaTypeArray {
+		o.MinItems = s.MinLength
+		o.MaxItems = s.MaxLength
+	} else {
+		o.MinLength = s.MinLength
+		o.MaxLength = s.MaxLength
+	}
+
+	if s.Items != nil {
+		o.Items = s.Items.OpenAPI()
+	}
+
+	if len(s.Properties) > 0 {
+		o.Properties = map[string]*OpenAPISchema{}
+
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			ps := s.Properties[name]
+
+			o.Properties[name] = ps.OpenAPI()
+			if ps.Required {
+				o.Required = append(o.Required, name)
+			}
+		}
+	}
+
+	return o
+}
+
+func routeMethods(route Route) (methods []string) {
+	for _, method := range route.Methods {
+		methods = append(methods, strings.ToLower(method))
+	}
+
+	if len(methods) == 0 {
+		methods = []string{"get"}
+
+		if route.Schema != nil && route.Schema.Body != nil {
+			methods = append(methods, "post")
+		}
+	}
+
+	return methods
+}
+
+// openAPIPath converts capture groups of a route regex into OpenAPI path parameters.
+// Parameters are named after the capture group or its index in the "matches" argument.
+// Patterns outside of capture groups are also turned into parameters
+// as OpenAPI path templates can only consist of literals and parameters.
+func openAPIPath(regex string) (string, []OpenAPIParameter) {
+	re, err := syntax.Parse(regex, syntax.Perl)
+	if err != nil {
+		return regex, nil
+	}
+
+	nodes := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		nodes = re.Sub
+	}
+
+	var (
+		path     strings.Builder
+		params   []OpenAPIParameter
+		implicit int
+	)
+
+	for _, node := range nodes {
+		var name string
+
+		switch node.Op {
+		case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText, syntax.OpEmptyMatch:
+			continue
+
+		case syntax.OpLiteral:
+			if node.Flags&syntax.FoldCase == 0 {
+				path.WriteString(string(node.Rune))
+				continue
+			}
+
+			name = fmt.Sprintf("path%d", implicit)
+			implicit++
+
+		case syntax.OpCapture:
+			name = node.Name
+			if name == "" {
+				name = fmt.Sprintf("match%d", node.Cap-1)
+			}
+
+			node = node.Sub[0]
+
+		default:
+			name = fmt.Sprintf("path%d", implicit)
+			implicit++
+		}
+
+		params = append(params, OpenAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema: &OpenAPISchema{
+				Type:    SchemaTypeString,
+				Pattern: "^(?:" + node.String() + ")$",
+			},
+		})
+
+		fmt.Fprintf(&path, "{%s}", name)
+	}
+
+	return path.String(), params
+}
+
+func (h *Handler) serveOpenAPI(wr http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		wr.Header().Set("Allow", "GET, HEAD")
+		http.Error(wr, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	wr.Header().Set("Content-Type", "application/json")
+	util.DumpJSONf(wr, h.OpenAPI())
+}
@@ -0,0 +1,118 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler_test
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/handler"
+)
+
+const testRoutes = `[
+	{ "path": "/", "match": "equals", "description": "Home" },
+	{
+		"path": "/person",
+		"match": "prefix",
+		"routes": [
+			{ "path": "/", "match": "equals", "description": "Help" },
+			{ "path": "/([^/]+)/(address|phone)", "match": "regex", "methods": [ "GET", "DELETE" ] }
+		]
+	},
+	{
+		"path": "/search",
+		"match": "equals",
+		"schema": {
+			"query": { "q": { "type": "string", "required": true } },
+			"body": { "type": "object", "properties": { "name": { "type": "string", "required": true } } }
+		}
+	}
+]`
+
+func TestOpenAPI(t *testing.T) {
+	h := &handler.Handler{}
+	if err := json.Unmarshal([]byte(testRoutes), &h.InspectResult.Routes); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := h.OpenAPI()
+
+	paths := []string{}
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+
+	if expected := []string{"/", "/person/", "/person/{match0}/{match1}", "/search"}; !slices.Equal(paths, expected) {
+		t.Fatalf("Paths = %v, want %v", paths, expected)
+	}
+
+	if op := doc.Paths["/"]["get"]; op == nil || op.Description != "Home" {
+		t.Errorf("Missing or wrong operation for /: %+v", op)
+	}
+
+	regexItem := doc.Paths["/person/{match0}/{match1}"]
+	if _, ok := regexItem["delete"]; !ok {
+		t.Errorf("Missing DELETE operation for regex route")
+	}
+
+	if params := regexItem["get"].Parameters; len(params) != 2 || params[1].In != "path" || params[1].Schema.Pattern != "^(?:address|phone)$" {
+		t.Errorf("Wrong path parameters: %+v", params)
+	}
+
+	search := doc.Paths["/search"]
+	if _, ok := search["post"]; !ok {
+		t.Fatalf("Missing POST operation for route with body schema")
+	}
+
+	if params := search["get"].Parameters; len(params) != 1 || params[0].Name != "q" || !params[0].Required {
+		t.Errorf("Wrong query parameters: %+v", params)
+	}
+
+	body := search["post"].RequestBody.Content["application/json"].Schema
+	if !slices.Equal(body.Required, []string{"name"}) {
+		t.Errorf("Required = %v, want [name]", body.Required)
+	}
+}
+
+func TestOpenAPIRegexPaths(t *testing.T) {
+	for _, tc := range []struct {
+		regex  string
+		path   string
+		params []string
+	}{
+		{`/person/([^/]+)`, "/person/{match0}", []string{"match0"}},
+		{`^/files/(?:a|b)\.txt$`, "/files/{path0}.txt", []string{"path0"}},
+		{`/escaped\(([0-9]+)\)`, "/escaped({match0})", []string{"match0"}},
+		{`/(?P<user>[a-z]+)/(([0-9]+)-([0-9]+))`, "/{user}/{match1}", []string{"user", "match1"}},
+	} {
+		t.Run(tc.regex, func(t *testing.T) {
+			h := &handler.Handler{}
+			h.InspectResult.Routes = []handler.Route{
+				{Path: tc.regex, Match: handler.RouteMatchRegex, Methods: []string{"GET", "POST"}},
+			}
+
+			doc := h.OpenAPI()
+
+			item, ok := doc.Paths[tc.path]
+			if !ok {
+				t.Fatalf("Missing path %s: %v", tc.path, doc.Paths)
+			}
+
+			names := []string{}
+			for _, p := range item["get"].Parameters {
+				names = append(names, p.Name)
+			}
+
+			if !slices.Equal(names, tc.params) {
+				t.Errorf("Parameters = %v, want %v", names, tc.params)
+			}
+
+			if item["get"] == item["post"] {
+				t.Error("Methods share an operation")
+			}
+		})
+	}
+}
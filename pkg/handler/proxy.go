@@ -0,0 +1,125 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/proxy"
+)
+
+func (r *Request) proxy() error {
+	if r.result.Type != options.DerivationType && r.result.Type != options.PathType {
+		return fmt.Errorf("invalid combination of type and mode")
+	}
+
+	if !r.handler.checkPath(r.body) {
+		return ForbiddenPathError(r.body)
+	}
+
+	// Services outlive the request, so there is no way to pass the secrets of a request to them.
+	if len(r.result.Secrets) > 0 || len(r.result.SecretFiles) > 0 {
+		return fmt.Errorf("secrets are not supported in the %s mode", options.ProxyMode)
+	}
+
+	spec := r.serviceSpec(r.body)
+	spec.Args = append(slices.Clone(r.handler.opts.RunArgs), r.result.Args...)
+
+	inst, release, err := r.acquireService(r.request.Context(), spec)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	// The request body has already been consumed when it was imported into the store.
+	if body, err := r.importedBody(); err != nil {
+		return err
+	} else if body != nil {
+		defer body.Close() //nolint:errcheck
+
+		r.request.Body = body
+	}
+
+	slog.Debug("Proxying request",
+		slog.String("service", spec.Name),
+		slog.Int("pid", inst.PID()))
+
+	r.writeHeader(0)
+	r.headersWritten = true
+
+	inst.ServeHTTP(r.response, r.request)
+
+	return nil
+}
+
+// serviceSpec describes the long-running service started from the program in a path.
+func (r *Request) serviceSpec(path string) proxy.Spec {
+	// Services are identified by the package name of the store path by default.
+	// So a rebuilt derivation replaces the running instance.
+	name := r.result.Service
+	if name == "" {
+		if strings.HasPrefix(path, r.handler.env.StoreDir) {
+			pi := nix.PathInfo{Path: path}
+			name, _ = nix.ParseName(pi.Name())
+		} else {
+			name = filepath.Base(path)
+		}
+	}
+
+	env := []string{}
+	for key, value := range r.result.Env {
+		env = append(env, key+"="+value)
+	}
+	slices.Sort(env)
+
+	return proxy.Spec{
+		Name:    name,
+		Program: filepath.Join(path, r.result.SubPath),
+		Env:     env,
+		Network: r.result.Listen,
+	}
+}
+
+func (r *Request) acquireService(ctx context.Context, spec proxy.Spec) (inst *proxy.Instance, release func(), err error) {
+	if r.handler.supervisor == nil {
+		return nil, nil, ForbiddenModeError(r.result.Mode)
+	}
+
+	// Services outlive the request, so they can neither share its sandbox nor its dedicated user.
+	if r.sandboxed() {
+		return nil, nil, fmt.Errorf("services can not be sandboxed")
+	}
+
+	if r.handler.users != nil {
+		return nil, nil, fmt.Errorf("services can not be run as dedicated users")
+	}
+
+	if spec.Limits, err = r.limits(); err != nil {
+		return nil, nil, err
+	}
+
+	switch spec.Network {
+	case "":
+		spec.Network = proxy.NetworkUnix
+	case proxy.NetworkUnix, proxy.NetworkTCP:
+	default:
+		return nil, nil, fmt.Errorf("invalid listen network: %s", spec.Network)
+	}
+
+	r.measure("start", func() {
+		inst, release, err = r.handler.supervisor.Acquire(ctx, spec)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start service '%s': %w", spec.Name, err)
+	}
+
+	return inst, release, nil
+}
@@ -14,15 +14,22 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"al.essio.dev/pkg/shellescape"
 	"github.com/stv0g/nixpresso/pkg"
 	"github.com/stv0g/nixpresso/pkg/cache"
+	"github.com/stv0g/nixpresso/pkg/gateway"
+	"github.com/stv0g/nixpresso/pkg/limits"
 	"github.com/stv0g/nixpresso/pkg/nix"
 	"github.com/stv0g/nixpresso/pkg/options"
+	"github.com/stv0g/nixpresso/pkg/sandbox"
+	"github.com/stv0g/nixpresso/pkg/secrets"
+	"github.com/stv0g/nixpresso/pkg/users"
 	"github.com/stv0g/nixpresso/pkg/util"
 )
 
@@ -36,15 +43,45 @@ type Request struct {
 	body           string
 	headersWritten bool
 	timings        map[string]time.Duration
+
+	// Values of the secrets injected into the program which are redacted from logs and errors
+	redactor secrets.Redactor
 }
 
 func (r *Request) Handle() (err error) {
+	route := r.handler.route(r.request)
+
+	if route != nil && len(route.Methods) > 0 {
+		if r.request.Method == http.MethodOptions {
+			r.response.Header().Set("Allow", route.Allow())
+			r.writeHeader(http.StatusNoContent)
+			return nil
+		}
+
+		if !route.AllowsMethod(r.request.Method) {
+			r.response.Header().Set("Allow", route.Allow())
+			r.writeHeader(http.StatusMethodNotAllowed)
+			return nil
+		}
+	}
+
+	schemas := []*RequestSchema{r.handler.InspectResult.Schema, util.Zero(route).Schema}
+	for _, schema := range slices.Compact(schemas) {
+		if schema == nil {
+			continue
+		}
+
+		if err := schema.Validate(r.request); err != nil {
+			return err
+		}
+	}
+
 	if r.arguments, err = r.handler.ArgumentsFromRequest(r.request); err != nil {
 		return fmt.Errorf("failed to assemble arguments: %w", err)
 	}
 
 	if err = r.handle(); err != nil {
-		slog.Error("Failed to handle request", slog.Any("error", err))
+		slog.Error("Failed to handle request", slog.String("error", r.redactor.Redact(err.Error())))
 
 		if _, ok := r.handler.InspectResult.ExpectedArgs["error"]; !ok {
 			return err
@@ -53,7 +90,7 @@ func (r *Request) Handle() (err error) {
 		// In case the handler can handle errors, we pass the error and the previous evaluation result
 		// to the handler and evaluate again
 		r.arguments.Result = r.result
-		r.arguments.Error = NewError(err)
+		r.arguments.Error = NewError(err).Redact(&r.redactor)
 		r.result = nil
 
 		if err = r.handle(); err != nil {
@@ -69,6 +106,20 @@ func (r *Request) handle() error {
 		return fmt.Errorf("failed to evaluate: %w", err)
 	}
 
+	// The handler deferred the import of the request body, but now requests it.
+	// So we import it into the store and evaluate again.
+	if r.result.NeedBody && r.arguments.BodyHash == nil {
+		if err := r.arguments.addBody(r.request); err != nil {
+			return err
+		}
+
+		r.result = nil
+
+		if err := r.eval(); err != nil {
+			return fmt.Errorf("failed to evaluate: %w", err)
+		}
+	}
+
 	if !slices.Contains(r.handler.opts.AllowedModes, r.result.Mode) {
 		return ForbiddenModeError(r.result.Mode)
 	}
@@ -83,7 +134,7 @@ func (r *Request) handle() error {
 	}
 
 	r.body = r.result.Body
-	if r.body == "" {
+	if r.body == "" && r.result.Type != options.ValueType {
 		r.writeHeader(r.result.Status)
 		return nil
 	}
@@ -99,6 +150,19 @@ func (r *Request) handle() error {
 		if err := r.build(); err != nil {
 			return fmt.Errorf("failed to build: %w", err)
 		}
+
+		// The handler requested multiple outputs and can post-process their paths.
+		// So we pass the result including the built output paths and evaluate again.
+		if r.canPostProcess() {
+			for name := range r.result.Headers {
+				hdr.Del(name)
+			}
+
+			r.arguments.Result = r.result
+			r.result = nil
+
+			return r.handle()
+		}
 	}
 
 	switch r.result.Mode {
@@ -121,6 +185,31 @@ func (r *Request) handle() error {
 		if err := r.derivation(); err != nil {
 			return fmt.Errorf("failed to get derivation: %w", err)
 		}
+
+	case options.ListingMode:
+		if err := r.listing(); err != nil {
+			return fmt.Errorf("failed to list directory: %w", err)
+		}
+
+	case options.ClosureMode:
+		if err := r.closure(); err != nil {
+			return fmt.Errorf("failed to get closure: %w", err)
+		}
+
+	case options.DiffMode:
+		if err := r.diff(); err != nil {
+			return fmt.Errorf("failed to diff closures: %w", err)
+		}
+
+	case options.ProxyMode:
+		if err := r.proxy(); err != nil {
+			return fmt.Errorf("failed to proxy: %w", err)
+		}
+
+	case options.CacheMode:
+		if err := r.binaryCache(); err != nil {
+			return fmt.Errorf("failed to serve binary cache: %w", err)
+		}
 	}
 
 	return nil
@@ -218,15 +307,25 @@ func (r *Request) build() (err error) {
 		stderr = r.response
 	}
 
+	outputs := r.result.Outputs
+	if len(outputs) == 0 {
+		outputs = []string{r.result.output()}
+	}
+
+	var paths map[string]string
 	durBuild := r.measure("build", func() {
 		ctx, cancel := context.WithTimeout(r.request.Context(), r.handler.opts.MaxBuildTime)
-		r.body, err = nix.Build(ctx, r.body, r.result.Output, r.result.PTY, r.handler.opts.Verbose, stderr, argv...)
+		paths, err = nix.BuildOutputs(ctx, r.body, outputs, r.result.PTY, r.handler.opts.Verbose, stderr, argv...)
 		cancel()
 	})
 	if err != nil {
 		return err
 	}
 
+	if err := r.selectOutput(paths); err != nil {
+		return err
+	}
+
 	slog.Info("Finished build",
 		slog.Any("result", r.body),
 		slog.Duration("after", durBuild))
@@ -234,6 +333,43 @@ func (r *Request) build() (err error) {
 	return nil
 }
 
+// selectOutput records the paths of the built outputs and selects the requested one as body.
+// The evaluation result might be shared with other requests via the eval cache.
+// Hence, the paths are stored in a copy of it.
+func (r *Request) selectOutput(paths map[string]string) error {
+	result := *r.result
+	result.OutputPaths = paths
+	r.result = &result
+
+	if path, ok := paths[r.result.output()]; ok {
+		r.body = path
+	} else if !r.canPostProcess() {
+		return fmt.Errorf("output '%s' has not been built", r.result.output())
+	}
+
+	return nil
+}
+
+// importedBody opens the request body which has been imported into the store.
+// The body sent by the client has been consumed by the import.
+// So it is read from the store, even if the handler requested to stream it.
+// It returns nil if the body has not been imported.
+func (r *Request) importedBody() (*os.File, error) {
+	if r.arguments.Body == nil {
+		return nil, nil
+	}
+
+	body, err := os.Open(*r.arguments.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open request body '%s': %w", *r.arguments.Body, err)
+	}
+
+	return body, nil
+}
+
+// validTerm matches terminal types like "xterm-256color" which are passed via $TERM.
+var validTerm = regexp.MustCompile(`^[a-zA-Z0-9._+-]{1,64}$`)
+
 func (r *Request) run() (err error) {
 	if r.result.Type != options.DerivationType && r.result.Type != options.PathType {
 		return fmt.Errorf("invalid combination of type and mode")
@@ -243,17 +379,40 @@ func (r *Request) run() (err error) {
 		return ForbiddenPathError(r.body)
 	}
 
+	if r.result.Protocol != "" && !slices.Contains(gateway.AllProtocols, r.result.Protocol) {
+		return fmt.Errorf("invalid protocol: %s", r.result.Protocol)
+	}
+
 	var (
 		stdin          io.Reader
 		stdout, stderr io.Writer
 		combined       = &bytes.Buffer{}
+		cgiStderr      = &bytes.Buffer{}
+		cgiOut         *cgiWriter
 		pty            int
+		term           *util.Terminal
+		termType       string
+		contentLength  = r.request.ContentLength
+		root           = r.body
 	)
 
 	r.body = filepath.Join(r.body, r.result.SubPath)
 
 	if r.result.PTY {
 		pty = util.StdinPTY | util.StdoutPTY | util.StderrPTY
+		term = util.NewTerminal(r.result.PTYRows, r.result.PTYCols)
+
+		// Terminal types are often taken from the request. So invalid ones fall back to the default.
+		if termType = r.result.Term; termType != "" && !validTerm.MatchString(termType) {
+			slog.Warn("Ignoring invalid terminal type", slog.String("term", termType))
+			termType = ""
+		}
+
+		// Resizing streamed programs requires reading the request while the response is written.
+		// HTTP/2 supports this natively, HTTP/1.1 must be switched before the response header is written.
+		if r.result.Stream {
+			http.NewResponseController(r.response).EnableFullDuplex() //nolint:errcheck
+		}
 	}
 
 	if r.result.Stream {
@@ -264,47 +423,190 @@ func (r *Request) run() (err error) {
 		stderr = combined
 	}
 
-	r.writeHeader(r.result.Status)
+	// The exit status of streamed programs is only known after the headers have been sent.
+	if r.result.Stream {
+		r.response.Header().Add("Trailer", TrailerExitStatus+", "+TrailerExitSignal)
+	}
+
+	// CGI programs decide about the status and headers at runtime.
+	// Their standard error is logged instead of being mixed into the response.
+	if r.result.CGI || r.result.Protocol != "" {
+		cgiOut = &cgiWriter{
+			r:      r,
+			body:   stdout,
+			stream: r.result.Stream,
+		}
+
+		stdout = cgiOut
+		stderr = cgiStderr
+	} else if r.result.Stream {
+		r.writeHeader(r.result.Status)
+	}
 
 	argv := []string{}
 	argv = append(argv, r.handler.opts.RunArgs...)
 	argv = append(argv, r.result.Args...)
 
-	if r.arguments.Body != nil {
-		body, err := os.Open(*r.arguments.Body)
-		if err != nil {
-			return fmt.Errorf("failed to open request body '%s': %w", *r.arguments.Body, err)
-		}
+	if body, err := r.importedBody(); err != nil {
+		return err
+	} else if body != nil {
 		defer body.Close() //nolint:errcheck
 
+		if fi, err := body.Stat(); err == nil {
+			contentLength = fi.Size()
+		}
+
 		stdin = body
 	} else {
 		stdin = r.request.Body
-	}
 
-	slog.Debug("Starting run: " + shellescape.QuoteCommand(append([]string{r.body}, argv...)))
+		// Clients resize the PTY of streamed programs by sending resize sequences in the request body.
+		if term != nil && r.result.Stream {
+			stdin = term.ResizeReader(stdin)
+		}
+	}
 
-	var cmd *exec.Cmd
+	var rc int
 	durRun := r.measure("run", func() {
 		ctx, cancel := context.WithTimeout(r.request.Context(), r.handler.opts.MaxRunTime)
-		cmd = exec.CommandContext(ctx, r.body, argv...)
+		defer cancel()
+
+		if r.result.Protocol != "" {
+			rc, err = r.runGateway(ctx, root, argv, stdin, contentLength, stdout, stderr)
+			return
+		}
+
+		slog.Debug("Starting run: " + shellescape.QuoteCommand(append([]string{r.body}, argv...)))
+
+		var l *limits.Limits
+		if l, err = r.limits(); err != nil {
+			return
+		}
+
+		var u *users.User
+		if u, err = r.handler.users.Acquire(ctx); err != nil {
+			return
+		}
+		defer func() {
+			if err := u.Release(); err != nil {
+				slog.Error("Failed to release user", slog.Any("error", err))
+			}
+		}()
+
+		var (
+			secretsEnv []string
+			secretsDir string
+		)
+		secretsEnv, secretsDir, err = r.secrets(u)
+		if secretsDir != "" {
+			defer os.RemoveAll(secretsDir) //nolint:errcheck
+		}
+		if err != nil {
+			return
+		}
+
+		// Rlimits and seccomp profiles are applied by the sandbox itself as they would also restrict its setup.
+		var cmd *exec.Cmd
+		if r.sandboxed() {
+			var cfg *sandbox.Config
+			if cfg, err = r.sandboxConfig(ctx, root, l); err != nil {
+				return
+			}
+
+			cfg.User = u
+			if secretsDir != "" {
+				cfg.Paths = append(cfg.Paths, secretsDir)
+			}
+
+			if cmd, err = sandbox.Command(ctx, cfg, r.body, argv...); err != nil {
+				return
+			}
+		} else {
+			cmd = exec.CommandContext(ctx, r.body, argv...)
+
+			if err = limits.Wrap(cmd, l); err != nil {
+				return
+			}
+		}
+
+		var cg *limits.Cgroup
+		if cg, err = limits.NewCgroup(r.handler.opts.Cgroup, l); err != nil {
+			return
+		}
+
+		cg.Attach(cmd)
+
+		if r.result.CGI {
+			cmd.Env = CGIEnvironment(r.request, r.handler.opts.BasePath, contentLength)
+		}
 		for key, value := range r.result.Env {
 			cmd.Env = append(cmd.Env, key+"="+value)
 		}
+		cmd.Env = append(cmd.Env, secretsEnv...)
+		if term != nil && termType != "" {
+			cmd.Env = append(cmd.Env, "TERM="+termType)
+		}
 
-		_, _, err = util.Run(cmd, pty, r.handler.opts.Verbose, stdin, stdout, stderr)
-		cancel()
+		if !r.sandboxed() {
+			if err = u.Apply(cmd); err != nil {
+				return
+			}
+		}
+
+		_, _, err = util.RunInTerminal(cmd, pty, term, r.handler.opts.Verbose, stdin, stdout, stderr)
+		err = r.seccompViolation(cg.Finish(err))
+		if cmd.ProcessState != nil {
+			rc = cmd.ProcessState.ExitCode()
+		}
 	})
+
+	if cgiStderr.Len() > 0 {
+		slog.Warn("CGI program wrote to standard error", slog.String("stderr", r.redactor.Redact(cgiStderr.String())))
+	}
+
+	var signal string
 	if err != nil {
-		return err
+		var ok bool
+		if rc, signal, ok = ExitStatus(err); !ok {
+			return err
+		}
+	}
+
+	if r.result.Stream {
+		hdr := r.response.Header()
+		hdr.Set(TrailerExitStatus, strconv.Itoa(rc))
+		if signal != "" {
+			hdr.Set(TrailerExitSignal, signal)
+		}
+	}
+
+	status, mapped := MapExitStatus(r.result.ExitStatus, rc, signal)
+	if !mapped {
+		// The status of streamed responses has already been sent.
+		// So the exit status is only reported via the trailers.
+		if err != nil && !r.result.Stream {
+			return err
+		}
+
+		status = r.result.Status
+	}
+
+	if cgiOut != nil && !mapped {
+		if !cgiOut.parsed {
+			return fmt.Errorf("missing CGI response header")
+		}
+
+		status = cgiOut.status
 	}
 
 	if !r.result.Stream {
 		hdr := r.response.Header()
-		hdr.Set("Content-Type", "text/plain")
+		if hdr.Get("Content-Type") == "" {
+			hdr.Set("Content-Type", "text/plain")
+		}
 		hdr.Set("Content-Length", fmt.Sprint(combined.Len()))
 
-		r.writeHeader(r.result.Status)
+		r.writeHeader(status)
 
 		if _, err := r.response.Write(combined.Bytes()); err != nil {
 			return fmt.Errorf("failed to write response: %w", err)
@@ -312,7 +614,9 @@ func (r *Request) run() (err error) {
 	}
 
 	slog.Info("Finished run",
-		slog.Int("rc", cmd.ProcessState.ExitCode()),
+		slog.Int("rc", rc),
+		slog.String("signal", signal),
+		slog.Int("status", status),
 		slog.Duration("after", durRun))
 
 	return nil
@@ -321,6 +625,8 @@ func (r *Request) run() (err error) {
 func (r *Request) serve() (err error) {
 	var modTime time.Time
 	var rd io.ReadSeeker
+	var size int64
+	var sibling, encoding string
 
 	switch r.result.Type {
 	case options.StringType:
@@ -329,6 +635,10 @@ func (r *Request) serve() (err error) {
 		}
 
 		rd = strings.NewReader(r.result.Body)
+		size = int64(len(r.result.Body))
+
+	case options.ValueType:
+		return r.serveValue()
 
 	case options.PathType, options.DerivationType:
 		r.body = filepath.Join(r.body, r.result.SubPath)
@@ -341,12 +651,22 @@ func (r *Request) serve() (err error) {
 			return ForbiddenPathError(r.body)
 		}
 
+		if r.result.Archive != "" {
+			return r.serveArchive()
+		}
+
+		if r.response.Header().Get("Content-Encoding") == "" {
+			sibling, encoding = r.precompressed(r.body)
+		}
+
 		if fi, err := os.Stat(r.body); err != nil {
 			return fmt.Errorf("failed to stat response body path '%s': %w", r.body, err)
-		} else if int(fi.Size()) > int(r.handler.opts.MaxResponseBytes) {
-			return fmt.Errorf("response body exceeds maximum size: %d > %d Bytes", len(r.result.Body), r.handler.opts.MaxResponseBytes)
+		} else if size = fi.Size(); size > r.handler.opts.MaxResponseBytes {
+			return fmt.Errorf("response body exceeds maximum size: %d > %d Bytes", size, r.handler.opts.MaxResponseBytes)
 		} else if isStorePath := strings.HasPrefix(r.body, r.handler.env.StoreDir); !isStorePath {
 			modTime = fi.ModTime()
+		} else if r.result.Immutable && r.response.Header().Get("Cache-Control") == "" {
+			r.response.Header().Set("Cache-Control", immutableCacheControl)
 		}
 
 		if f, err := os.Open(r.body); err != nil {
@@ -359,6 +679,42 @@ func (r *Request) serve() (err error) {
 		return fmt.Errorf("invalid combination of type and mode")
 	}
 
+	hdr := r.response.Header()
+	hdr.Add("Vary", "Accept-Encoding")
+
+	ct := hdr.Get("Content-Type")
+	if ct == "" {
+		if ct, err = detectContentType(r.body, rd); err != nil {
+			return fmt.Errorf("failed to detect content type: %w", err)
+		}
+
+		hdr.Set("Content-Type", ct)
+	}
+
+	// Precompressed siblings are served like the file itself, including range requests
+	if sibling != "" {
+		f, err := os.Open(sibling)
+		if err != nil {
+			return fmt.Errorf("failed to open precompressed sibling: %w", err)
+		}
+		defer f.Close() //nolint:errcheck
+
+		slog.Debug("Serving precompressed sibling", slog.String("path", sibling), slog.String("encoding", encoding))
+
+		hdr.Set("Content-Encoding", encoding)
+		rd = f
+
+		r.setStoreETag(sibling)
+	} else {
+		if r.result.Type != options.StringType {
+			r.setStoreETag(r.body)
+		}
+
+		if encoding := r.compressOnTheFly(ct, size); encoding != "" {
+			return r.serveCompressed(encoding, rd)
+		}
+	}
+
 	r.writeHeader(0) // WriteHeader() is called by ServeContent()
 	http.ServeContent(r.response, r.request, r.body, modTime, rd)
 
@@ -446,10 +802,33 @@ func (r *Request) writeError(err error) {
 
 	hdr := r.response.Header()
 	hdr.Del("Content-Length")
-	hdr.Set("Content-Type", "text/plain; charset=utf-8")
 	hdr.Set("X-Content-Type-Options", "nosniff")
 
-	http.Error(r.response, err.Error(), http.StatusInternalServerError)
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		hdr.Set("Content-Type", "application/json; charset=utf-8")
+		r.response.WriteHeader(http.StatusBadRequest)
+
+		util.DumpJSONf(r.response, struct {
+			Error  string            `json:"error"`
+			Issues []ValidationIssue `json:"issues"`
+		}{
+			Error:  ve.Error(),
+			Issues: ve.Issues,
+		})
+
+		return
+	}
+
+	hdr.Set("Content-Type", "text/plain; charset=utf-8")
+
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		http.Error(r.response, fmt.Sprintf("request body must not be larger than %d bytes", mbe.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	http.Error(r.response, r.redactor.Redact(err.Error()), http.StatusInternalServerError)
 }
 
 func (r *Request) measure(id string, cb func()) time.Duration {
@@ -493,11 +872,25 @@ func (r *Request) evalArgs(forCache bool) ([]string, error) {
 	return argv, nil
 }
 
+// canPostProcess checks if the handler can be evaluated again with the paths of the built outputs.
+func (r *Request) canPostProcess() bool {
+	_, ok := r.handler.InspectResult.ExpectedArgs["result"]
+
+	return ok && len(r.result.Outputs) > 0 && r.arguments.Result == nil
+}
+
 func (r *Request) canEvalCache() bool {
 	if r.handler.cache == nil {
 		return false
 	}
 
+	// Results of handlers which depend on per-request values must not be shared.
+	for _, arg := range volatileArgs {
+		if _, ok := r.handler.InspectResult.ExpectedArgs[arg]; ok {
+			return false
+		}
+	}
+
 	if ccHdr := r.request.Header.Get("Cache-Control"); ccHdr != "" {
 		for _, ccDirective := range strings.Split(ccHdr, ",") {
 			ccDirective = strings.TrimSpace(ccDirective)
@@ -3,19 +3,137 @@
 
 package handler
 
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+	"github.com/stv0g/nixpresso/pkg/options"
+)
+
 type EvalResult struct {
 	Status  int                 `json:"status,omitempty"`
 	Headers map[string][]string `json:"headers,omitempty"`
 
-	Mode      string            `json:"mode,omitempty"`
-	Type      string            `json:"type,omitempty"`
-	Body      string            `json:"body,omitempty"`
-	SubPath   string            `json:"subPath,omitempty"`
-	Args      []string          `json:"args,omitempty"`
-	Env       map[string]string `json:"env,omitempty"`
-	Output    string            `json:"output,omitempty"`
-	Stream    bool              `json:"stream,omitempty"`
-	Recursive bool              `json:"recursive,omitempty"`
-	Rebuild   bool              `json:"rebuild,omitempty"`
-	PTY       bool              `json:"pty,omitempty"`
+	Mode         string            `json:"mode,omitempty"`
+	Type         string            `json:"type,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	SubPath      string            `json:"subPath,omitempty"`
+	Args         []string          `json:"args,omitempty"`
+	Env          map[string]string `json:"env,omitempty"`
+	Output       string            `json:"output,omitempty"`
+	Outputs      []string          `json:"outputs,omitempty"`
+	OutputPaths  map[string]string `json:"outputPaths,omitempty"`
+	Stream       bool              `json:"stream,omitempty"`
+	Recursive    bool              `json:"recursive,omitempty"`
+	Rebuild      bool              `json:"rebuild,omitempty"`
+	PTY          bool              `json:"pty,omitempty"`
+	PTYRows      int               `json:"ptyRows,omitempty"`
+	PTYCols      int               `json:"ptyCols,omitempty"`
+	Term         string            `json:"term,omitempty"`
+	CGI          bool              `json:"cgi,omitempty"`
+	Archive      string            `json:"archive,omitempty"`
+	Compression  string            `json:"compression,omitempty"`
+	Immutable    bool              `json:"immutable,omitempty"`
+	WhyDepends   string            `json:"whyDepends,omitempty"`
+	DiffBase     string            `json:"diffBase,omitempty"`
+	Listen       string            `json:"listen,omitempty"`
+	Service      string            `json:"service,omitempty"`
+	Protocol     string            `json:"protocol,omitempty"`
+	Workers      int               `json:"workers,omitempty"`
+	ExitStatus   map[string]int    `json:"exitStatus,omitempty"`
+	Sandbox      bool              `json:"sandbox,omitempty"`
+	Capabilities []string          `json:"capabilities,omitempty"`
+
+	SeccompProfile string `json:"seccompProfile,omitempty"`
+
+	// Names of operator-configured secrets which are injected at runtime without passing through Nix
+	Secrets     map[string]string `json:"secrets,omitempty"`
+	SecretFiles []string          `json:"secretFiles,omitempty"`
+
+	// Request body handling
+	NeedBody bool `json:"needBody,omitempty"`
+
+	// Body of the "value" type which is serialized by Nixpresso.
+	// It is (un)marshaled as body in JSON and Nix, so it is passed to the handler again when the result is re-evaluated.
+	Value json.RawMessage `json:"-"`
+}
+
+// MarshalJSON emits the value as body for the "value" type.
+func (r *EvalResult) MarshalJSON() ([]byte, error) {
+	type evalResult EvalResult
+
+	if r.Type != options.ValueType {
+		return json.Marshal((*evalResult)(r))
+	}
+
+	return json.Marshal(struct {
+		*evalResult
+		Body json.RawMessage `json:"body,omitempty"`
+	}{
+		evalResult: (*evalResult)(r),
+		Body:       r.Value,
+	})
+}
+
+// MarshalNix emits the value as body for the "value" type when the result is passed to the handler again.
+func (r *EvalResult) MarshalNix() (string, error) {
+	type evalResult EvalResult
+
+	res, err := nix.Marshal((*evalResult)(r), "")
+	if err != nil {
+		return "", err
+	}
+
+	if r.Type != options.ValueType || len(r.Value) == 0 {
+		return res, nil
+	}
+
+	v, err := DecodeValue(r.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	body, err := nix.Marshal(v, "")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("(%s // { body = %s; })", res, body), nil
+}
+
+// UnmarshalJSON accepts arbitrary values as body for the "value" type.
+func (r *EvalResult) UnmarshalJSON(b []byte) error {
+	type evalResult EvalResult
+
+	aux := struct {
+		*evalResult
+		Body json.RawMessage `json:"body,omitempty"`
+	}{
+		evalResult: (*evalResult)(r),
+	}
+
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	if r.Type == options.ValueType {
+		r.Value = aux.Body
+		return nil
+	}
+
+	if len(aux.Body) > 0 {
+		return json.Unmarshal(aux.Body, &r.Body)
+	}
+
+	return nil
+}
+
+// output returns the name of the derivation output which is served, run or logged.
+func (r *EvalResult) output() string {
+	if r.Output == "" {
+		return "out"
+	}
+
+	return r.Output
 }
@@ -0,0 +1,122 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	RouteMatchEquals = "equals"
+	RouteMatchPrefix = "prefix"
+	RouteMatchRegex  = "regex"
+)
+
+// Route is collected from the predicates of "lib.handlers.router".
+type Route struct {
+	Path         string          `json:"path"`
+	Match        string          `json:"match,omitempty"`
+	Description  string          `json:"description,omitempty"`
+	Methods      []string        `json:"methods,omitempty"`
+	ExpectedArgs map[string]bool `json:"expectedArgs,omitempty"`
+	Schema       *RequestSchema  `json:"schema,omitempty"`
+	Routes       []Route         `json:"routes,omitempty"`
+}
+
+// Flatten returns all leaf routes with the paths of their parent routes prepended.
+func (r Route) Flatten(prefix string) (routes []Route) {
+	path := prefix + r.Path
+
+	if len(r.Routes) == 0 {
+		leaf := r
+		leaf.Path = path
+
+		return []Route{leaf}
+	}
+
+	for _, sr := range r.Routes {
+		routes = append(routes, sr.Flatten(path)...)
+	}
+
+	return routes
+}
+
+// Allow returns the value of the "Allow" header for the methods of the route.
+func (r Route) Allow() string {
+	methods := slices.Clone(r.Methods)
+
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+
+	return strings.Join(methods, ", ")
+}
+
+// AllowsMethod checks if the route accepts the method.
+// Routes which do not declare their methods accept all methods.
+func (r Route) AllowsMethod(method string) bool {
+	if len(r.Methods) == 0 {
+		return true
+	}
+
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	return slices.Contains(r.Methods, method)
+}
+
+func (r Route) matches(path string) (remainder string, ok bool) {
+	switch r.Match {
+	case RouteMatchEquals:
+		return "", path == r.Path
+
+	case RouteMatchPrefix:
+		return strings.CutPrefix(path, r.Path)
+
+	case RouteMatchRegex:
+		re, err := regexp.Compile("^(?:" + r.Path + ")$")
+		if err != nil {
+			return "", false
+		}
+
+		return "", re.MatchString(path)
+	}
+
+	return "", false
+}
+
+func flattenRoutes(routes []Route) (flat []Route) {
+	for _, r := range routes {
+		flat = append(flat, r.Flatten("")...)
+	}
+
+	return flat
+}
+
+// matchRoute returns the leaf route which the router will select for the path.
+// It returns nil if the path is handled by a default handler.
+func matchRoute(routes []Route, path string) *Route {
+	for _, r := range routes {
+		remainder, ok := r.matches(path)
+		if !ok {
+			continue
+		}
+
+		if len(r.Routes) == 0 {
+			return &r
+		}
+
+		return matchRoute(r.Routes, remainder)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/handler"
+)
+
+func TestRoutes(t *testing.T) {
+	h := &handler.Handler{}
+	if err := json.Unmarshal([]byte(testRoutes), &h.InspectResult.Routes); err != nil {
+		t.Fatal(err)
+	}
+
+	routes := h.Routes()
+
+	paths := []string{}
+	for _, route := range routes {
+		paths = append(paths, route.Path)
+	}
+
+	if expected := []string{"/", "/person/", "/person/([^/]+)/(address|phone)", "/search"}; !slices.Equal(paths, expected) {
+		t.Errorf("Routes() = %v, want %v", paths, expected)
+	}
+}
+
+func TestRouteMethods(t *testing.T) {
+	h := &handler.Handler{}
+	if err := json.Unmarshal([]byte(testRoutes), &h.InspectResult.Routes); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name     string
+		Method   string
+		Target   string
+		Status   int
+		Expected string
+	}{
+		{
+			Name:     "options",
+			Method:   http.MethodOptions,
+			Target:   "/person/stv0g/phone",
+			Status:   http.StatusNoContent,
+			Expected: "GET, DELETE, HEAD, OPTIONS",
+		},
+		{
+			Name:     "method not allowed",
+			Method:   http.MethodPost,
+			Target:   "/person/stv0g/address",
+			Status:   http.StatusMethodNotAllowed,
+			Expected: "GET, DELETE, HEAD, OPTIONS",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.Method, tt.Target, nil)
+
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.Status {
+				t.Errorf("Status = %d, want %d", rec.Code, tt.Status)
+			}
+
+			if allow := rec.Header().Get("Allow"); allow != tt.Expected {
+				t.Errorf("Allow = %q, want %q", allow, tt.Expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/options"
+)
+
+func TestRunStreamExitStatus(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		stream bool
+		err    bool
+	}{
+		{"buffered", false, true},
+		{"streamed", true, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r := &Request{
+				handler: &Handler{
+					opts: options.Options{
+						AllowedPaths: options.Paths{"/bin/"},
+						MaxRunTime:   10 * time.Second,
+					},
+				},
+				request:  httptest.NewRequest("GET", "/", nil),
+				response: rec,
+				timings:  map[string]time.Duration{},
+				body:     "/bin/sh",
+				result: &EvalResult{
+					Type:   options.PathType,
+					Mode:   options.RunMode,
+					Status: 200,
+					Stream: tc.stream,
+					Args:   []string{"-c", "echo output; exit 3"},
+				},
+			}
+
+			err := r.run()
+			if tc.err {
+				if err == nil {
+					t.Fatal("Expected error for unmapped exit status")
+				}
+
+				return
+			} else if err != nil {
+				t.Fatalf("Unexpected error for streamed response: %v", err)
+			}
+
+			if rec.Code != 200 || rec.Body.String() != "output\n" {
+				t.Errorf("Unexpected response: %d %q", rec.Code, rec.Body.String())
+			}
+
+			if status := rec.Result().Trailer.Get(TrailerExitStatus); status != "3" {
+				t.Errorf("Unexpected exit status trailer: %q", status)
+			}
+		})
+	}
+}
+
+func TestRunInvalidTerm(t *testing.T) {
+	rec := httptest.NewRecorder()
+	r := &Request{
+		handler: &Handler{
+			opts: options.Options{
+				AllowedPaths: options.Paths{"/bin/"},
+				MaxRunTime:   10 * time.Second,
+			},
+		},
+		request:  httptest.NewRequest("GET", "/", nil),
+		response: rec,
+		timings:  map[string]time.Duration{},
+		body:     "/bin/sh",
+		result: &EvalResult{
+			Type:   options.PathType,
+			Mode:   options.RunMode,
+			Status: 200,
+			PTY:    true,
+			Term:   "xterm; rm -rf /",
+			Args:   []string{"-c", `echo "TERM=$TERM"`},
+		},
+	}
+
+	// Invalid terminal types from the request fall back to the default
+	if err := r.run(); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := rec.Body.String(); strings.Contains(out, "rm -rf") || !strings.Contains(out, "TERM=") {
+		t.Errorf("Unexpected output: %q", out)
+	}
+}
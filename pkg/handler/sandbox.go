@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/nix"
+	"github.com/stv0g/nixpresso/pkg/sandbox"
+)
+
+func (r *Request) sandboxed() bool {
+	return r.handler.opts.Sandbox || r.result.Sandbox
+}
+
+// sandboxConfig exposes only the closure of the program and the request body to the sandbox.
+func (r *Request) sandboxConfig(ctx context.Context, root string, l *limits.Limits) (*sandbox.Config, error) {
+	if err := sandbox.ValidateCapabilities(r.result.Capabilities, r.handler.opts.AllowedCapabilities); err != nil {
+		return nil, err
+	}
+
+	paths := []string{root}
+
+	if rel, ok := strings.CutPrefix(root, r.handler.env.StoreDir+"/"); ok {
+		storePath := r.handler.env.StoreDir + "/" + strings.SplitN(rel, "/", 2)[0]
+
+		infos, err := nix.PathInfos(ctx, r.handler.opts.Verbose, true, storePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get closure: %w", err)
+		}
+
+		paths = nil
+		for _, info := range infos {
+			paths = append(paths, info.Path)
+		}
+	}
+
+	if r.arguments.Body != nil {
+		paths = append(paths, *r.arguments.Body)
+	}
+
+	// The sandbox drops capabilities itself but retains those requested by the handler.
+	sl := *l
+	sl.DropCapabilities = false
+
+	return &sandbox.Config{
+		Paths:        paths,
+		Capabilities: r.result.Capabilities,
+		Limits:       &sl,
+	}, nil
+}
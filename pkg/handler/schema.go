@@ -0,0 +1,376 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	SchemaTypeString  = "string"
+	SchemaTypeInteger = "integer"
+	SchemaTypeNumber  = "number"
+	SchemaTypeBoolean = "boolean"
+	SchemaTypeObject  = "object"
+	SchemaTypeArray   = "array"
+
+	// MaxSchemaBodyBytes limits the size of request bodies which are buffered to validate them against a schema.
+	MaxSchemaBodyBytes = 1 << 20
+)
+
+// RequestSchema describes the query parameters, headers and body a handler accepts.
+// It is declared by the handler in its "meta.schema" attribute.
+type RequestSchema struct {
+	Query   map[string]*Schema `json:"query,omitempty"`
+	Headers map[string]*Schema `json:"headers,omitempty"`
+	Body    *Schema            `json:"body,omitempty"`
+}
+
+type Schema struct {
+	Type        string             `json:"type,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Required    bool               `json:"required,omitempty"`
+	Enum        []any              `json:"enum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+
+	pattern *regexp.Regexp
+}
+
+// UnmarshalJSON compiles the pattern once when the schema is loaded.
+func (s *Schema) UnmarshalJSON(b []byte) error {
+	type schema Schema
+
+	if err := json.Unmarshal(b, (*schema)(s)); err != nil {
+		return err
+	}
+
+	if s.Pattern != "" {
+		var err error
+		if s.pattern, err = regexp.Compile(s.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern in schema: %w", err)
+		}
+	}
+
+	return nil
+}
+
+type ValidationIssue struct {
+	Location string `json:"location"`
+	Message  string `json:"message"`
+}
+
+type ValidationError struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := []string{}
+	for _, issue := range e.Issues {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", issue.Location, issue.Message))
+	}
+
+	return fmt.Sprintf("invalid request: %s", strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) add(loc, format string, args ...any) {
+	e.Issues = append(e.Issues, ValidationIssue{
+		Location: loc,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Validate checks the request against the schema.
+// If the schema describes the body, up to MaxSchemaBodyBytes of it are read into memory
+// and prepended to the remainder of the body. Larger bodies are rejected.
+func (s *RequestSchema) Validate(req *http.Request) error {
+	ve := &ValidationError{}
+
+	query := req.URL.Query()
+	for _, name := range slices.Sorted(maps.Keys(s.Query)) {
+		s.Query[name].validateValues(ve, "query."+name, query[name])
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s.Headers)) {
+		s.Headers[name].validateValues(ve, "headers."+name, req.Header.Values(name))
+	}
+
+	if s.Body != nil {
+		if err := s.validateBody(ve, req); err != nil {
+			return err
+		}
+	}
+
+	if len(ve.Issues) > 0 {
+		return ve
+	}
+
+	return nil
+}
+
+func (s *RequestSchema) validateBody(ve *ValidationError, req *http.Request) error {
+	var body []byte
+
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(io.LimitReader(req.Body, MaxSchemaBodyBytes+1)); err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+
+		if len(body) > MaxSchemaBodyBytes {
+			ve.add("body", "must not be larger than %d bytes", MaxSchemaBodyBytes)
+			return nil
+		}
+	}
+
+	if len(body) == 0 {
+		if s.Body.Required {
+			ve.add("body", "is required")
+		}
+
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	switch mediaType {
+	case "application/json":
+		var v any
+
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			ve.add("body", "malformed JSON: %s", err)
+			return nil
+		}
+
+		s.Body.validate(ve, "body", v)
+
+	case "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			ve.add("body", "malformed form: %s", err)
+			return nil
+		}
+
+		s.Body.validateForm(ve, "body", form)
+
+	default:
+		if s.Body.Type != "" && s.Body.Type != SchemaTypeString {
+			ve.add("body", "unsupported content type '%s'", mediaType)
+			return nil
+		}
+
+		s.Body.validate(ve, "body", string(body))
+	}
+
+	return nil
+}
+
+// validateValues validates string values originating from query parameters or headers.
+func (s *Schema) validateValues(ve *ValidationError, loc string, values []string) {
+	if len(values) == 0 {
+		if s.Required {
+			ve.add(loc, "is required")
+		}
+
+		return
+	}
+
+	if s.Type == SchemaTypeArray {
+		s.checkLength(ve, loc, len(values))
+
+		if s.Items != nil {
+			for i, value := range values {
+				s.Items.validateString(ve, fmt.Sprintf("%s[%d]", loc, i), value)
+			}
+		}
+
+		return
+	}
+
+	if len(values) > 1 {
+		ve.add(loc, "must be given at most once")
+		return
+	}
+
+	s.validateString(ve, loc, values[0])
+}
+
+// validateForm validates a decoded HTML form in which all values are strings.
+func (s *Schema) validateForm(ve *ValidationError, loc string, form url.Values) {
+	if s.Type != "" && s.Type != SchemaTypeObject {
+		ve.add(loc, "must be of type %s", s.Type)
+		return
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+		s.Properties[name].validateValues(ve, loc+"."+name, form[name])
+	}
+}
+
+// validateString coerces a string to the type of the schema before validating it.
+func (s *Schema) validateString(ve *ValidationError, loc, str string) {
+	var v any = str
+
+	switch s.Type {
+	case SchemaTypeInteger, SchemaTypeNumber:
+		v = json.Number(str)
+
+	case SchemaTypeBoolean:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			ve.add(loc, "must be of type %s", s.Type)
+			return
+		}
+
+		v = b
+	}
+
+	s.validate(ve, loc, v)
+}
+
+func (s *Schema) validate(ve *ValidationError, loc string, v any) {
+	if v == nil {
+		if s.Required {
+			ve.add(loc, "is required")
+		}
+
+		return
+	}
+
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool {
+		return enumEqual(e, v)
+	}) {
+		ve.add(loc, "must be one of %v", s.Enum)
+	}
+
+	switch w := v.(type) {
+	case string:
+		if s.Type != "" && s.Type != SchemaTypeString {
+			ve.add(loc, "must be of type %s", s.Type)
+			return
+		}
+
+		s.checkLength(ve, loc, utf8.RuneCountInString(w))
+
+		if s.pattern != nil && !s.pattern.MatchString(w) {
+			ve.add(loc, "must match pattern '%s'", s.Pattern)
+		}
+
+	case json.Number:
+		var (
+			f   float64
+			err error
+		)
+
+		switch s.Type {
+		case SchemaTypeInteger:
+			var i int64
+			i, err = w.Int64()
+			f = float64(i)
+		case SchemaTypeNumber, "":
+			f, err = w.Float64()
+		default:
+			ve.add(loc, "must be of type %s", s.Type)
+			return
+		}
+		if err != nil {
+			ve.add(loc, "must be of type %s", s.Type)
+			return
+		}
+
+		if s.Minimum != nil && f < *s.Minimum {
+			ve.add(loc, "must be at least %v", *s.Minimum)
+		}
+
+		if s.Maximum != nil && f > *s.Maximum {
+			ve.add(loc, "must be at most %v", *s.Maximum)
+		}
+
+	case bool:
+		if s.Type != "" && s.Type != SchemaTypeBoolean {
+			ve.add(loc, "must be of type %s", s.Type)
+		}
+
+	case []any:
+		if s.Type != "" && s.Type != SchemaTypeArray {
+			ve.add(loc, "must be of type %s", s.Type)
+			return
+		}
+
+		s.checkLength(ve, loc, len(w))
+
+		if s.Items != nil {
+			for i, item := range w {
+				s.Items.validate(ve, fmt.Sprintf("%s[%d]", loc, i), item)
+			}
+		}
+
+	case map[string]any:
+		if s.Type != "" && s.Type != SchemaTypeObject {
+			ve.add(loc, "must be of type %s", s.Type)
+			return
+		}
+
+		for _, name := range slices.Sorted(maps.Keys(s.Properties)) {
+			s.Properties[name].validate(ve, loc+"."+name, w[name])
+		}
+	}
+}
+
+// enumEqual compares a value with an enum member. Numbers are compared by their value, e.g. "1.0" equals 1.
+func enumEqual(e, v any) bool {
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return false
+		}
+
+		switch e := e.(type) {
+		case float64:
+			return f == e
+		case json.Number:
+			g, err := e.Float64()
+			return err == nil && f == g
+		}
+
+		return false
+	}
+
+	return reflect.DeepEqual(e, v)
+}
+
+func (s *Schema) checkLength(ve *ValidationError, loc string, n int) {
+	if s.MinLength != nil && n < *s.MinLength {
+		ve.add(loc, "must have a length of at least %d", *s.MinLength)
+	}
+
+	if s.MaxLength != nil && n > *s.MaxLength {
+		ve.add(loc, "must have a length of at most %d", *s.MaxLength)
+	}
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/handler"
+)
+
+const testSchema = `{
+	"query": {
+		"user": { "type": "string", "required": true, "maxLength": 8 },
+		"limit": { "type": "integer", "minimum": 1, "maximum": 100 },
+		"sort": { "type": "string", "enum": [ "asc", "desc" ] },
+		"scale": { "type": "number", "enum": [ 1, 2.5 ] }
+	},
+	"headers": {
+		"X-Token": { "type": "string", "pattern": "^[a-f0-9]+$" }
+	},
+	"body": {
+		"type": "object",
+		"properties": {
+			"name": { "type": "string", "required": true },
+			"tags": { "type": "array", "maxLength": 2, "items": { "type": "string" } }
+		}
+	}
+}`
+
+func TestRequestSchemaValidate(t *testing.T) {
+	var schema handler.RequestSchema
+	if err := json.Unmarshal([]byte(testSchema), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Name        string
+		Target      string
+		Headers     map[string]string
+		Body        string
+		ContentType string
+		Expected    []handler.ValidationIssue
+	}{
+		{
+			Name:   "valid query",
+			Target: "/?user=stv0g&limit=10&sort=asc",
+		},
+		{
+			Name:     "missing required query parameter",
+			Target:   "/",
+			Expected: []handler.ValidationIssue{{Location: "query.user", Message: "is required"}},
+		},
+		{
+			Name:   "invalid query parameters",
+			Target: "/?user=averylongname&limit=abc&sort=up",
+			Expected: []handler.ValidationIssue{
+				{Location: "query.limit", Message: "must be of type integer"},
+				{Location: "query.sort", Message: "must be one of [asc desc]"},
+				{Location: "query.user", Message: "must have a length of at most 8"},
+			},
+		},
+		{
+			Name:   "numeric enum",
+			Target: "/?user=stv0g&scale=1.0",
+		},
+		{
+			Name:     "numeric enum mismatch",
+			Target:   "/?user=stv0g&scale=2",
+			Expected: []handler.ValidationIssue{{Location: "query.scale", Message: "must be one of [1 2.5]"}},
+		},
+		{
+			Name:     "query parameter out of range",
+			Target:   "/?user=stv0g&limit=1000",
+			Expected: []handler.ValidationIssue{{Location: "query.limit", Message: "must be at most 100"}},
+		},
+		{
+			Name:     "invalid header",
+			Target:   "/?user=stv0g",
+			Headers:  map[string]string{"X-Token": "xyz"},
+			Expected: []handler.ValidationIssue{{Location: "headers.X-Token", Message: "must match pattern '^[a-f0-9]+$'"}},
+		},
+		{
+			Name:        "valid JSON body",
+			Target:      "/?user=stv0g",
+			Body:        `{"name": "test", "tags": ["a", "b"]}`,
+			ContentType: "application/json",
+		},
+		{
+			Name:        "invalid JSON body",
+			Target:      "/?user=stv0g",
+			Body:        `{"tags": ["a", "b", 3]}`,
+			ContentType: "application/json",
+			Expected: []handler.ValidationIssue{
+				{Location: "body.name", Message: "is required"},
+				{Location: "body.tags", Message: "must have a length of at most 2"},
+				{Location: "body.tags[2]", Message: "must be of type string"},
+			},
+		},
+		{
+			Name:        "malformed JSON body",
+			Target:      "/?user=stv0g",
+			Body:        `{"name": `,
+			ContentType: "application/json",
+			Expected:    []handler.ValidationIssue{{Location: "body", Message: "malformed JSON: unexpected EOF"}},
+		},
+		{
+			Name:        "form body",
+			Target:      "/?user=stv0g",
+			Body:        "tags=a",
+			ContentType: "application/x-www-form-urlencoded",
+			Expected:    []handler.ValidationIssue{{Location: "body.name", Message: "is required"}},
+		},
+		{
+			Name:        "large body",
+			Target:      "/?user=stv0g",
+			Body:        `{"name": "` + strings.Repeat("a", handler.MaxSchemaBodyBytes) + `"}`,
+			ContentType: "application/json",
+			Expected:    []handler.ValidationIssue{{Location: "body", Message: fmt.Sprintf("must not be larger than %d bytes", handler.MaxSchemaBodyBytes)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.Target, strings.NewReader(tt.Body))
+			if tt.ContentType != "" {
+				req.Header.Set("Content-Type", tt.ContentType)
+			}
+			for name, value := range tt.Headers {
+				req.Header.Set(name, value)
+			}
+
+			err := schema.Validate(req)
+
+			var issues []handler.ValidationIssue
+			var ve *handler.ValidationError
+			if errors.As(err, &ve) {
+				issues = ve.Issues
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(issues, tt.Expected) {
+				t.Errorf("Validate() = %v, want %v", issues, tt.Expected)
+			}
+
+			// The body must still be readable after validation
+			if body, err := io.ReadAll(req.Body); err != nil {
+				t.Fatal(err)
+			} else if string(body) != tt.Body {
+				t.Errorf("Body = %q, want %q", body, tt.Body)
+			}
+		})
+	}
+}
+
+func TestSchemaInvalidPattern(t *testing.T) {
+	var schema handler.RequestSchema
+	if err := json.Unmarshal([]byte(`{"query": {"q": {"pattern": "(["}}}`), &schema); err == nil {
+		t.Fatal("Expected error for invalid pattern")
+	}
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/seccomp"
+)
+
+// limits returns the limits of the program including the seccomp profiles selected by the operator and the handler.
+// Both profiles are installed as stacked filters, so the handler can only restrict the program further.
+func (r *Request) limits() (*limits.Limits, error) {
+	l := r.handler.opts.Limits
+	l.Seccomp = slices.Clone(l.Seccomp)
+
+	for _, name := range r.seccompProfiles() {
+		p, ok := r.handler.seccompProfiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown seccomp profile: %s", name)
+		}
+
+		l.Seccomp = append(l.Seccomp, p)
+	}
+
+	return &l, nil
+}
+
+func (r *Request) seccompProfiles() (names []string) {
+	for _, name := range []string{r.handler.opts.SeccompProfile, r.result.SeccompProfile} {
+		if name != "" && name != seccomp.NoProfileName && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// seccompViolation attributes a termination by SIGSYS to the installed seccomp profiles.
+func (r *Request) seccompViolation(err error) error {
+	names := r.seccompProfiles()
+	if len(names) == 0 {
+		return err
+	}
+
+	if _, signal, ok := ExitStatus(err); !ok || signal != "SIGSYS" {
+		return err
+	}
+
+	return &seccomp.ViolationError{
+		Err:      err,
+		Profiles: names,
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg/users"
+)
+
+// EnvCredentialsDirectory points programs to the files of their secrets like systemd's LoadCredential=.
+const EnvCredentialsDirectory = "CREDENTIALS_DIRECTORY"
+
+// secrets reads the secrets referenced by the handler and registers them for redaction.
+// Secrets are returned as environment variables. Secret files are written to a private directory
+// which the caller must remove after the program has exited.
+func (r *Request) secrets(u *users.User) (env []string, dir string, err error) {
+	for _, name := range slices.Sorted(maps.Keys(r.result.Secrets)) {
+		value, err := r.handler.secrets.Get(r.result.Secrets[name])
+		if err != nil {
+			return nil, "", err
+		}
+
+		r.redactor.Add(value)
+
+		env = append(env, name+"="+strings.TrimSpace(string(value)))
+	}
+
+	if len(r.result.SecretFiles) == 0 {
+		return env, "", nil
+	}
+
+	if dir, err = os.MkdirTemp("", "nixpresso-secrets-"); err != nil {
+		return nil, "", fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+
+	// The directory must remain writable for its removal.
+	paths := []string{dir}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return nil, dir, fmt.Errorf("failed to change mode of secrets directory: %w", err)
+	}
+
+	for _, name := range r.result.SecretFiles {
+		value, err := r.handler.secrets.Get(name)
+		if err != nil {
+			return nil, dir, err
+		}
+
+		r.redactor.Add(value)
+
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, value, 0o400); err != nil {
+			return nil, dir, fmt.Errorf("failed to write secret '%s': %w", name, err)
+		}
+
+		paths = append(paths, path)
+	}
+
+	if u != nil {
+		for _, path := range paths {
+			if err := os.Chown(path, int(u.UID), int(u.GID)); err != nil {
+				return nil, dir, fmt.Errorf("failed to change owner of secrets: %w", err)
+			}
+		}
+	}
+
+	return append(env, EnvCredentialsDirectory+"="+dir), dir, nil
+}
@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stv0g/nixpresso/pkg/nix"
+	"github.com/stv0g/nixpresso/pkg/util"
+	"go.yaml.in/yaml/v3"
+)
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeYAML = "application/yaml"
+	ContentTypeCBOR = "application/cbor"
+	ContentTypeNix  = "text/x-nix"
+)
+
+// ValueContentTypes are the formats into which values are serialized in order of preference.
+var ValueContentTypes = []string{ContentTypeJSON, ContentTypeYAML, ContentTypeCBOR, ContentTypeNix}
+
+// DecodeValue decodes a JSON value while preserving the distinction between integers and floats.
+func DecodeValue(raw []byte) (any, error) {
+	var v any
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return normalizeNumbers(v), nil
+}
+
+func normalizeNumbers(v any) any {
+	switch w := v.(type) {
+	case json.Number:
+		if i, err := w.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := w.Float64()
+		return f
+
+	case []any:
+		for i, e := range w {
+			w[i] = normalizeNumbers(e)
+		}
+
+	case map[string]any:
+		for k, e := range w {
+			w[k] = normalizeNumbers(e)
+		}
+	}
+
+	return v
+}
+
+// EncodeValue serializes a decoded value into the format of the content type.
+func EncodeValue(v any, contentType string) ([]byte, error) {
+	switch contentType {
+	case ContentTypeJSON:
+		return json.MarshalIndent(v, "", "  ")
+
+	case ContentTypeYAML:
+		return yaml.Marshal(v)
+
+	case ContentTypeCBOR:
+		em, err := cbor.CanonicalEncOptions().EncMode()
+		if err != nil {
+			return nil, err
+		}
+
+		return em.Marshal(v)
+
+	case ContentTypeNix:
+		s, err := nix.Marshal(v, "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(s + "\n"), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported content type: %s", contentType)
+	}
+}
+
+func (r *Request) serveValue() error {
+	hdr := r.response.Header()
+	hdr.Add("Vary", "Accept")
+
+	contentType := util.Negotiate(r.request.Header.Get("Accept"), ValueContentTypes...)
+	if contentType == "" {
+		return r.notAcceptable(ValueContentTypes)
+	}
+
+	v, err := DecodeValue(r.result.Value)
+	if err != nil {
+		return fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	body, err := EncodeValue(v, contentType)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	if len(body) > int(r.handler.opts.MaxResponseBytes) {
+		return fmt.Errorf("response body exceeds maximum size: %d > %d Bytes", len(body), r.handler.opts.MaxResponseBytes)
+	}
+
+	if strings.HasPrefix(contentType, "text/") || contentType == ContentTypeJSON || contentType == ContentTypeYAML {
+		contentType += "; charset=utf-8"
+	}
+
+	hdr.Set("Content-Type", contentType)
+
+	r.writeHeader(0) // WriteHeader() is called by ServeContent()
+	http.ServeContent(r.response, r.request, "", time.Time{}, bytes.NewReader(body))
+
+	return nil
+}
+
+// notAcceptable responds with "406 Not Acceptable" and lists the available content types.
+func (r *Request) notAcceptable(offers []string) error {
+	hdr := r.response.Header()
+	hdr.Set("Content-Type", "text/plain; charset=utf-8")
+	hdr.Set("X-Content-Type-Options", "nosniff")
+
+	r.writeHeader(http.StatusNotAcceptable)
+
+	_, err := fmt.Fprintf(r.response, "None of the available content types is acceptable: %s\n", strings.Join(offers, ", "))
+
+	return err
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stv0g/nixpresso/pkg/handler"
+	"github.com/stv0g/nixpresso/pkg/nix"
+	"github.com/stv0g/nixpresso/pkg/options"
+)
+
+const testValue = `{"name": "hello", "version": 2, "ratio": 0.5, "tags": ["a", null], "meta": {"broken": false}}`
+
+func TestEncodeValue(t *testing.T) {
+	v, err := handler.DecodeValue([]byte(testValue))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		handler.ContentTypeJSON: `{
+  "meta": {
+    "broken": false
+  },
+  "name": "hello",
+  "ratio": 0.5,
+  "tags": [
+    "a",
+    null
+  ],
+  "version": 2
+}`,
+		handler.ContentTypeYAML: `meta:
+    broken: false
+name: hello
+ratio: 0.5
+tags:
+    - a
+    - null
+version: 2
+`,
+		handler.ContentTypeNix: `{
+  meta = {
+    broken = false;
+  };
+  name = "hello";
+  ratio = 0.5;
+  tags = [
+    "a"
+    null
+  ];
+  version = 2;
+}
+`,
+	}
+
+	for contentType, expected := range tests {
+		t.Run(contentType, func(t *testing.T) {
+			body, err := handler.EncodeValue(v, contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(body) != expected {
+				t.Errorf("EncodeValue() = %s, want %s", body, expected)
+			}
+		})
+	}
+
+	t.Run(handler.ContentTypeCBOR, func(t *testing.T) {
+		body, err := handler.EncodeValue(v, handler.ContentTypeCBOR)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded map[string]any
+		if err := cbor.Unmarshal(body, &decoded); err != nil {
+			t.Fatal(err)
+		}
+
+		if decoded["version"] != uint64(2) || decoded["name"] != "hello" {
+			t.Errorf("decoded = %v", decoded)
+		}
+	})
+
+	if _, err := handler.EncodeValue(v, "text/html"); err == nil {
+		t.Error("expected error for unsupported content type")
+	}
+}
+
+func TestEvalResultValue(t *testing.T) {
+	var res handler.EvalResult
+	if err := json.Unmarshal([]byte(`{"type": "value", "body": {"a": [1, 2]}}`), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Type != options.ValueType || !bytes.Equal(res.Value, []byte(`{"a": [1, 2]}`)) || res.Body != "" {
+		t.Errorf("res = %+v", res)
+	}
+
+	if err := json.Unmarshal([]byte(`{"type": "string", "body": "hello", "status": 200}`), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Body != "hello" || res.Status != 200 {
+		t.Errorf("res = %+v", res)
+	}
+}
+
+func TestEvalResultValueRoundTrip(t *testing.T) {
+	var res handler.EvalResult
+	if err := json.Unmarshal([]byte(`{"type": "value", "status": 200, "body": `+testValue+`}`), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	// Results are passed to the handler again when it is re-evaluated, e.g. for errors
+	b, err := json.Marshal(&res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var res2 handler.EvalResult
+	if err := json.Unmarshal(b, &res2); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected bytes.Buffer
+	if err := json.Compact(&expected, []byte(testValue)); err != nil {
+		t.Fatal(err)
+	}
+
+	if res2.Type != options.ValueType || res2.Status != 200 || !bytes.Equal(res2.Value, expected.Bytes()) {
+		t.Errorf("Value has not been preserved: %s", b)
+	}
+}
+
+func TestEvalResultValueNix(t *testing.T) {
+	var res handler.EvalResult
+	if err := json.Unmarshal([]byte(`{"type": "value", "status": 200, "body": {"name": "hello", "ratio": 0.5}}`), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	// Results are passed as Nix expression to the handler when it is re-evaluated
+	args, err := nix.Marshal(handler.Arguments{Result: &res}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{ error = null; result = ({ status = 200; type = "value"; } // { body = { name = "hello"; ratio = 0.5; }; }); tls = null; }`; args != expected {
+		t.Errorf("Unexpected arguments:\n%s\nwant:\n%s", args, expected)
+	}
+}
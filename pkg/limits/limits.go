@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package limits restricts the resources of child processes via rlimits and cgroup v2 quotas.
+//
+// Rlimits are applied via a re-execution of the current binary right before the program is executed.
+// Binaries using this package must therefore call Init first thing in their main function.
+package limits
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/seccomp"
+)
+
+const initArg0 = "nixpresso-limits-init"
+
+var ErrUnsupported = errors.New("resource limits are not supported on this platform")
+
+// Limits of a child process. Zero values mean no limit.
+type Limits struct {
+	// Applied via setrlimit(2)
+	AddressSpace int64         `json:"addressSpace,omitempty"`
+	CPUTime      time.Duration `json:"cpuTime,omitempty"`
+	OpenFiles    int64         `json:"openFiles,omitempty"`
+	FileSize     int64         `json:"fileSize,omitempty"`
+
+	// Applied via a cgroup v2.
+	// The number of processes is not limited via RLIMIT_NPROC as it counts all processes of the user.
+	Memory    int64   `json:"memory,omitempty"`
+	CPU       float64 `json:"cpu,omitempty"`
+	Processes int64   `json:"processes,omitempty"`
+
+	// Installed as seccomp filters after the rlimits
+	Seccomp []*seccomp.Profile `json:"seccomp,omitempty"`
+
+	// Clears all capabilities before executing the program. Capabilities which a non-root
+	// service holds in its ambient set would otherwise be inherited by the program.
+	DropCapabilities bool `json:"dropCapabilities,omitempty"`
+}
+
+// needsInit checks if the limits must be applied by a re-execution before the program.
+func (l *Limits) needsInit() bool {
+	return l != nil && (l.AddressSpace > 0 || l.CPUTime > 0 || l.OpenFiles > 0 || l.FileSize > 0 || len(l.Seccomp) > 0 || l.DropCapabilities)
+}
+
+func (l *Limits) hasQuotas() bool {
+	return l != nil && (l.Memory > 0 || l.CPU > 0 || l.Processes > 0)
+}
+
+// OOMError indicates that processes have been killed because they exceeded the memory quota.
+type OOMError struct {
+	Err   error
+	Kills int
+}
+
+func (e *OOMError) Error() string {
+	return fmt.Sprintf("%s (%d processes killed due to exceeded memory limit)", e.Err, e.Kills)
+}
+
+func (e *OOMError) Unwrap() error {
+	return e.Err
+}
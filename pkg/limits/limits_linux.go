@@ -0,0 +1,285 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package limits
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const cpuPeriod = 100000 // µs
+
+// Apply sets the rlimits of the current process, drops its capabilities and installs its seccomp profiles.
+// It must be called right before executing the program as the Go runtime itself might not start with a limited address space.
+func (l *Limits) Apply() error {
+	if l == nil {
+		return nil
+	}
+
+	for resource, limit := range map[int]int64{
+		unix.RLIMIT_AS:     l.AddressSpace,
+		unix.RLIMIT_CPU:    int64(math.Ceil(l.CPUTime.Seconds())),
+		unix.RLIMIT_NOFILE: l.OpenFiles,
+		unix.RLIMIT_FSIZE:  l.FileSize,
+	} {
+		if limit <= 0 {
+			continue
+		}
+
+		rlim := &unix.Rlimit{Cur: uint64(limit), Max: uint64(limit)}
+		if err := unix.Setrlimit(resource, rlim); err != nil {
+			return fmt.Errorf("failed to set rlimit %d: %w", resource, err)
+		}
+	}
+
+	if l.DropCapabilities {
+		if err := dropCapabilities(); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range l.Seccomp {
+		if err := p.Install(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// dropCapabilities clears the ambient, inheritable, permitted and effective capabilities of the current thread.
+// The init process is locked to its thread as the program is executed from it.
+func dropCapabilities() error {
+	if err := unix.Prctl(unix.PR_CAP_AMBIENT, unix.PR_CAP_AMBIENT_CLEAR_ALL, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to clear ambient capabilities: %w", err)
+	}
+
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+	if err := unix.Capset(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("failed to clear capabilities: %w", err)
+	}
+
+	return nil
+}
+
+// HasAmbientCapabilities checks if the current process holds ambient capabilities which are inherited by its children,
+// e.g. those granted by systemd's AmbientCapabilities= to a non-root service.
+func HasAmbientCapabilities() bool {
+	status, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return false
+	}
+
+	for line := range strings.Lines(string(status)) {
+		if value, ok := strings.CutPrefix(line, "CapAmb:"); ok {
+			caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+			return err == nil && caps != 0
+		}
+	}
+
+	return false
+}
+
+// Wrap changes the command to apply the rlimits before executing the program.
+func Wrap(cmd *exec.Cmd, l *Limits) error {
+	if !l.needsInit() {
+		return nil
+	}
+
+	if cmd.Err != nil {
+		return cmd.Err
+	}
+
+	c, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode limits: %w", err)
+	}
+
+	cmd.Args = append([]string{initArg0, string(c), cmd.Path}, cmd.Args...)
+	cmd.Path = "/proc/self/exe"
+
+	return nil
+}
+
+// Init applies the rlimits and executes the program if the process has been started by a wrapped command.
+// Otherwise, it returns immediately.
+func Init() {
+	if len(os.Args) < 4 || os.Args[0] != initArg0 {
+		return
+	}
+
+	// Capabilities are a property of the thread which executes the program.
+	runtime.LockOSThread()
+
+	var l Limits
+	if err := json.Unmarshal([]byte(os.Args[1]), &l); err != nil {
+		fmt.Fprintf(os.Stderr, "limits: failed to decode: %s\n", err)
+		os.Exit(127)
+	}
+
+	if err := l.Apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "limits: %s\n", err)
+		os.Exit(127)
+	}
+
+	err := unix.Exec(os.Args[2], os.Args[3:], os.Environ())
+	fmt.Fprintf(os.Stderr, "limits: failed to execute %s: %s\n", os.Args[2], err)
+	os.Exit(127)
+}
+
+// Cgroup is a cgroup v2 into which a single command is placed.
+// The methods of a nil Cgroup are no-ops.
+type Cgroup struct {
+	path string
+	dir  *os.File
+}
+
+// NewCgroup creates a cgroup below a delegated parent cgroup and configures the memory, CPU and process quotas.
+// It returns nil if no parent or quotas are given.
+func NewCgroup(parent string, l *Limits) (*Cgroup, error) {
+	if parent == "" || !l.hasQuotas() {
+		return nil, nil
+	}
+
+	// Controllers can only be enabled for cgroups without processes of their own.
+	for _, controller := range []string{"memory", "cpu", "pids"} {
+		if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+"+controller), 0); err != nil {
+			slog.Debug("Failed to enable cgroup controller", slog.String("controller", controller), slog.Any("error", err))
+		}
+	}
+
+	path, err := os.MkdirTemp(parent, "nixpresso-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+
+	cg := &Cgroup{
+		path: path,
+	}
+
+	settings := map[string]string{}
+	if l.Memory > 0 {
+		settings["memory.max"] = strconv.FormatInt(l.Memory, 10)
+		settings["memory.swap.max"] = "0"
+	}
+
+	if l.CPU > 0 {
+		settings["cpu.max"] = fmt.Sprintf("%d %d", int64(l.CPU*cpuPeriod), cpuPeriod)
+	}
+
+	if l.Processes > 0 {
+		settings["pids.max"] = strconv.FormatInt(l.Processes, 10)
+	}
+
+	for file, value := range settings {
+		if err := os.WriteFile(filepath.Join(path, file), []byte(value), 0); err != nil {
+			// Swap accounting is optional
+			if file == "memory.swap.max" && errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			cg.remove() //nolint:errcheck
+			return nil, fmt.Errorf("failed to set %s: %w", file, err)
+		}
+	}
+
+	if cg.dir, err = os.Open(path); err != nil {
+		cg.remove() //nolint:errcheck
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+
+	return cg, nil
+}
+
+// Attach lets the command start directly in the cgroup.
+func (cg *Cgroup) Attach(cmd *exec.Cmd) {
+	if cg == nil {
+		return
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(cg.dir.Fd())
+}
+
+// OOMKills returns the number of processes killed because the memory quota was exceeded.
+func (cg *Cgroup) OOMKills() int {
+	if cg == nil {
+		return 0
+	}
+
+	f, err := os.Open(filepath.Join(cg.path, "memory.events"))
+	if err != nil {
+		return 0
+	}
+	defer f.Close() //nolint:errcheck
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), "oom_kill "); ok {
+			n, _ := strconv.Atoi(value)
+			return n
+		}
+	}
+
+	return 0
+}
+
+// Finish removes the cgroup after the command has finished.
+// The error of the command is wrapped in an OOMError if processes were killed due to the memory quota.
+func (cg *Cgroup) Finish(err error) error {
+	if cg == nil {
+		return err
+	}
+
+	if n := cg.OOMKills(); n > 0 && err != nil {
+		err = &OOMError{Err: err, Kills: n}
+	}
+
+	if rerr := cg.remove(); rerr != nil {
+		slog.Warn("Failed to remove cgroup", slog.String("path", cg.path), slog.Any("error", rerr))
+	}
+
+	return err
+}
+
+func (cg *Cgroup) remove() error {
+	if cg.dir != nil {
+		cg.dir.Close() //nolint:errcheck
+	}
+
+	// Kill remaining processes, e.g. daemonized children of the command.
+	if err := os.WriteFile(filepath.Join(cg.path, "cgroup.kill"), []byte("1"), 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Debug("Failed to kill cgroup", slog.String("path", cg.path), slog.Any("error", err))
+	}
+
+	var err error
+	for range 10 {
+		if err = unix.Rmdir(cg.path); !errors.Is(err, unix.EBUSY) {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return err
+}
@@ -0,0 +1,136 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package limits_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"golang.org/x/sys/unix"
+)
+
+func TestMain(m *testing.M) {
+	limits.Init()
+
+	os.Exit(m.Run())
+}
+
+func TestWrap(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "ulimit -n; ulimit -f; ulimit -t")
+	if err := limits.Wrap(cmd, &limits.Limits{
+		OpenFiles: 42,
+		FileSize:  10 * 512,
+		CPUTime:   1500 * time.Millisecond,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != "42\n10\n2\n" {
+		t.Errorf("unexpected limits: %q", out)
+	}
+}
+
+func TestWrapWithoutLimits(t *testing.T) {
+	cmd := exec.Command("/bin/true")
+	if err := limits.Wrap(cmd, &limits.Limits{Memory: 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.Path != "/bin/true" {
+		t.Errorf("command must not be wrapped: %s", cmd.Path)
+	}
+}
+
+func TestWrapFileSize(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "trap '' XFSZ; head -c 4096 /dev/zero > "+t.TempDir()+"/file")
+	if err := limits.Wrap(cmd, &limits.Limits{FileSize: 1024}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cmd.Run(); err == nil {
+		t.Error("expected writing beyond the file size limit to fail")
+	}
+}
+
+// A delegated cgroup v2 with the memory controller is required, e.g.:
+// NIXPRESSO_TEST_CGROUP=/sys/fs/cgroup/user.slice/user-1000.slice/user@1000.service/app.slice
+func TestCgroup(t *testing.T) {
+	parent := os.Getenv("NIXPRESSO_TEST_CGROUP")
+	if parent == "" {
+		t.Skip("NIXPRESSO_TEST_CGROUP is not set")
+	}
+
+	l := &limits.Limits{
+		Memory: 16 << 20,
+		CPU:    0.5,
+	}
+
+	cg, err := limits.NewCgroup(parent, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", "cat /proc/self/cgroup; head -c 64M /dev/zero | tail")
+	cg.Attach(cmd)
+
+	out, err := cmd.Output()
+	err = cg.Finish(err)
+
+	if !strings.Contains(string(out), "/nixpresso-") {
+		t.Errorf("command not started in cgroup: %s", out)
+	}
+
+	var oe *limits.OOMError
+	if !errors.As(err, &oe) || oe.Kills == 0 {
+		t.Errorf("expected OOM error: %v", err)
+	}
+}
+
+func TestWrapDropCapabilities(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Raising ambient capabilities requires root")
+	}
+
+	// A non-root program with ambient capabilities like those of a service started with AmbientCapabilities=
+	run := func(l *limits.Limits) string {
+		cmd := exec.Command("/bin/sh", "-c", "grep -E '^Cap(Inh|Prm|Eff|Amb):' /proc/self/status")
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential:  &syscall.Credential{Uid: 64000, Gid: 64000},
+			AmbientCaps: []uintptr{unix.CAP_SETUID, unix.CAP_DAC_OVERRIDE},
+		}
+
+		if err := limits.Wrap(cmd, l); err != nil {
+			t.Fatal(err)
+		}
+
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return string(out)
+	}
+
+	if out := run(nil); !strings.Contains(out, "CapAmb:\t0000000000000082\n") {
+		t.Fatalf("ambient capabilities have not been raised:\n%s", out)
+	}
+
+	out := run(&limits.Limits{DropCapabilities: true})
+	for _, set := range []string{"CapInh", "CapPrm", "CapEff", "CapAmb"} {
+		if !strings.Contains(out, set+":\t0000000000000000\n") {
+			t.Errorf("capabilities have not been dropped:\n%s", out)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package limits
+
+import (
+	"os/exec"
+)
+
+// Init is a no-op on platforms without support for resource limits.
+func Init() {}
+
+// HasAmbientCapabilities always returns false on platforms without capabilities.
+func HasAmbientCapabilities() bool {
+	return false
+}
+
+// Apply is not supported on this platform.
+func (l *Limits) Apply() error {
+	if l.needsInit() {
+		return ErrUnsupported
+	}
+
+	return nil
+}
+
+// Wrap is not supported on this platform.
+func Wrap(_ *exec.Cmd, l *Limits) error {
+	if l.needsInit() {
+		return ErrUnsupported
+	}
+
+	return nil
+}
+
+// Cgroup is not supported on this platform.
+type Cgroup struct{}
+
+// NewCgroup is not supported on this platform.
+func NewCgroup(parent string, l *Limits) (*Cgroup, error) {
+	if parent != "" && l.hasQuotas() {
+		return nil, ErrUnsupported
+	}
+
+	return nil, nil
+}
+
+func (cg *Cgroup) Attach(_ *exec.Cmd) {}
+
+func (cg *Cgroup) OOMKills() int {
+	return 0
+}
+
+func (cg *Cgroup) Finish(err error) error {
+	return err
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package listing
+
+import (
+	"cmp"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	TypeRegular   = "regular"
+	TypeDirectory = "directory"
+	TypeSymlink   = "symlink"
+)
+
+const (
+	SortName = "name"
+	SortSize = "size"
+	SortType = "type"
+)
+
+var AllSortKeys = []string{SortName, SortSize, SortType}
+
+type Entry struct {
+	Name   string      `json:"name"`
+	Type   string      `json:"type"`
+	Size   int64       `json:"size"`
+	Mode   fs.FileMode `json:"mode"`
+	Target string      `json:"target,omitempty"`
+}
+
+// Read collects the entries of a directory.
+// Entries for which the check function fails are omitted.
+// For symlinks, the check function is also called for their resolved target.
+func Read(dir string, check func(path string) bool) (entries []Entry, err error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory '%s': %w", dir, err)
+	}
+
+	entries = []Entry{}
+
+	for _, de := range des {
+		path := filepath.Join(dir, de.Name())
+		if !check(path) {
+			continue
+		}
+
+		fi, err := de.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		e := Entry{
+			Name: de.Name(),
+			Mode: fi.Mode().Perm(),
+		}
+
+		switch {
+		case fi.Mode()&fs.ModeSymlink != 0:
+			if e.Target, err = os.Readlink(path); err != nil {
+				return nil, fmt.Errorf("failed to read symlink '%s': %w", path, err)
+			}
+
+			// Dangling symlinks and those pointing to forbidden paths are hidden
+			if target, err := filepath.EvalSymlinks(path); err != nil || !check(target) {
+				continue
+			}
+
+			e.Type = TypeSymlink
+
+		case fi.IsDir():
+			e.Type = TypeDirectory
+
+		case fi.Mode().IsRegular():
+			e.Type = TypeRegular
+			e.Size = fi.Size()
+
+		default:
+			continue
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// Sort orders entries by the given key.
+// Ties are broken by name.
+func Sort(entries []Entry, key string, desc bool) error {
+	var compare func(a, b Entry) int
+
+	switch key {
+	case SortName, "":
+		compare = func(a, b Entry) int { return 0 }
+	case SortSize:
+		compare = func(a, b Entry) int { return cmp.Compare(a.Size, b.Size) }
+	case SortType:
+		compare = func(a, b Entry) int { return strings.Compare(a.Type, b.Type) }
+	default:
+		return fmt.Errorf("invalid sort key: %s", key)
+	}
+
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		c := cmp.Or(compare(a, b), strings.Compare(a.Name, b.Name))
+		if desc {
+			return -c
+		}
+
+		return c
+	})
+
+	return nil
+}
+
+// Page returns the entries of a 1-indexed page.
+func Page(entries []Entry, page, limit int) []Entry {
+	if page < 1 || limit < 1 {
+		return []Entry{}
+	}
+
+	start := (page - 1) * limit
+	if start >= len(entries) {
+		return []Entry{}
+	}
+
+	return entries[start:min(start+limit, len(entries))]
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package listing_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/listing"
+)
+
+func testDir(t *testing.T) (string, string) {
+	tmp := t.TempDir()
+	root := filepath.Join(tmp, "dir")
+	secret := filepath.Join(tmp, "secret")
+
+	for _, dir := range []string{root, secret, filepath.Join(root, "sub")} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, content := range map[string]string{
+		"a.txt":  "aaa",
+		"b.txt":  "b",
+		"run.sh": "#!/bin/sh",
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name, target := range map[string]string{
+		"link":     "a.txt",
+		"dangling": "missing",
+		"escape":   "../secret",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root, secret
+}
+
+func names(entries []listing.Entry) (n []string) {
+	for _, e := range entries {
+		n = append(n, e.Name)
+	}
+
+	return n
+}
+
+func TestRead(t *testing.T) {
+	root, secret := testDir(t)
+
+	entries, err := listing.Read(root, func(path string) bool {
+		return !strings.HasPrefix(path, secret)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := listing.Sort(entries, listing.SortName, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := names(entries), []string{"a.txt", "b.txt", "link", "run.sh", "sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("names = %v, want %v", got, want)
+	}
+
+	link := entries[2]
+	if link.Type != listing.TypeSymlink || link.Target != "a.txt" {
+		t.Errorf("link = %+v", link)
+	}
+
+	if a := entries[0]; a.Type != listing.TypeRegular || a.Size != 3 || a.Mode != 0o644 {
+		t.Errorf("a.txt = %+v", a)
+	}
+
+	if sub := entries[4]; sub.Type != listing.TypeDirectory {
+		t.Errorf("sub = %+v", sub)
+	}
+}
+
+func TestSortAndPage(t *testing.T) {
+	entries := []listing.Entry{
+		{Name: "c", Type: listing.TypeRegular, Size: 1},
+		{Name: "a", Type: listing.TypeRegular, Size: 3},
+		{Name: "d", Type: listing.TypeDirectory},
+		{Name: "b", Type: listing.TypeRegular, Size: 1},
+	}
+
+	tests := []struct {
+		key  string
+		desc bool
+		want []string
+	}{
+		{listing.SortName, false, []string{"a", "b", "c", "d"}},
+		{listing.SortName, true, []string{"d", "c", "b", "a"}},
+		{listing.SortSize, false, []string{"d", "b", "c", "a"}},
+		{listing.SortType, false, []string{"d", "a", "b", "c"}},
+	}
+
+	for _, tt := range tests {
+		if err := listing.Sort(entries, tt.key, tt.desc); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := names(entries); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Sort(%s, %t) = %v, want %v", tt.key, tt.desc, got, tt.want)
+		}
+	}
+
+	if err := listing.Sort(entries, "mtime", false); err == nil {
+		t.Error("expected error for invalid sort key")
+	}
+
+	listing.Sort(entries, listing.SortName, false) //nolint:errcheck
+
+	if got := names(listing.Page(entries, 2, 3)); !reflect.DeepEqual(got, []string{"d"}) {
+		t.Errorf("Page(2, 3) = %v", got)
+	}
+
+	if got := listing.Page(entries, 3, 3); len(got) != 0 {
+		t.Errorf("Page(3, 3) = %v", got)
+	}
+}
@@ -5,14 +5,26 @@ package nix
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"maps"
 	"strings"
 
 	"github.com/stv0g/nixpresso/pkg/util"
 )
 
-func Build(ctx context.Context, drv string, output string, withPTY bool, verbose int, stderr io.Writer, argv ...string) (string, error) {
-	argv2 := []string{"build", "--no-link", "--print-out-paths", drv + "^" + output}
+// AllOutputs selects all outputs of a derivation.
+const AllOutputs = "*"
+
+type BuildResult struct {
+	DrvPath string            `json:"drvPath"`
+	Outputs map[string]string `json:"outputs"`
+}
+
+// BuildOutputs builds a set of outputs of a derivation in a single invocation.
+// It returns the paths of the built outputs by their names.
+func BuildOutputs(ctx context.Context, drv string, outputs []string, withPTY bool, verbose int, stderr io.Writer, argv ...string) (map[string]string, error) {
+	argv2 := []string{"build", "--no-link", drv + "^" + strings.Join(outputs, ",")}
 	argv2 = append(argv2, argv...)
 
 	pty := 0
@@ -20,10 +32,19 @@ func Build(ctx context.Context, drv string, output string, withPTY bool, verbose
 		pty = util.StdinPTY | util.StderrPTY
 	}
 
-	stdout, _, err := Nix(ctx, pty, verbose, nil, nil, stderr, argv2...)
-	if err != nil {
-		return "", err
+	var results []BuildResult
+	if err := NixUnmarshal(ctx, pty, verbose, &results, nil, stderr, argv2...); err != nil {
+		return nil, err
+	}
+
+	paths := map[string]string{}
+	for _, result := range results {
+		maps.Copy(paths, result.Outputs)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no outputs have been built")
 	}
 
-	return strings.TrimSpace(string(stdout)), nil
+	return paths, nil
 }
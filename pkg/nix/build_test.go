@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix_test
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+)
+
+const multiOutputExpr = `
+derivation {
+  name = "multi";
+  system = builtins.currentSystem;
+  builder = "/bin/sh";
+  args = [ "-c" "echo out > $out; echo dev > $dev; echo doc > $doc" ];
+  outputs = [ "out" "dev" "doc" ];
+}
+`
+
+func TestBuildOutputs(t *testing.T) {
+	if _, err := exec.LookPath("nix"); err != nil {
+		t.Skip("Nix is not available")
+	}
+
+	var drv string
+	if err := nix.Eval(context.Background(), false, 0, &drv, "--expr", multiOutputExpr, "--apply", "d: d.drvPath"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		outputs  []string
+		expected []string
+	}{
+		{[]string{"dev"}, []string{"dev"}},
+		{[]string{"out", "doc"}, []string{"out", "doc"}},
+		{[]string{nix.AllOutputs}, []string{"out", "dev", "doc"}},
+	} {
+		t.Run(strings.Join(tc.outputs, ","), func(t *testing.T) {
+			paths, err := nix.BuildOutputs(context.Background(), drv, tc.outputs, false, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(paths) != len(tc.expected) {
+				t.Errorf("Expected outputs %v, got %v", tc.expected, paths)
+			}
+
+			for _, name := range tc.expected {
+				suffix := "-multi-" + name
+				if name == "out" {
+					suffix = "-multi"
+				}
+
+				if path, ok := paths[name]; !ok || !strings.HasSuffix(path, suffix) {
+					t.Errorf("Missing or unexpected path for output %s: %v", name, paths)
+				}
+			}
+		})
+	}
+}
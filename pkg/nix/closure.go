@@ -0,0 +1,187 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Closure is the set of store paths which are referenced by a root path directly or indirectly.
+type Closure struct {
+	Root        string     `json:"root"`
+	Dependency  string     `json:"dependency,omitempty"`
+	Chain       []string   `json:"chain,omitempty"`
+	ClosureSize int64      `json:"closureSize"`
+	Paths       []PathInfo `json:"paths"`
+}
+
+func NewClosure(root string, infos []PathInfo) *Closure {
+	c := &Closure{
+		Root:  root,
+		Paths: infos,
+	}
+
+	for _, info := range infos {
+		c.ClosureSize += info.NarSize
+	}
+
+	return c
+}
+
+// Name returns the name part of the store path.
+func (pi *PathInfo) Name() string {
+	base := pi.Path[strings.LastIndexByte(pi.Path, '/')+1:]
+	_, name, _ := strings.Cut(base, "-")
+	return name
+}
+
+func (c *Closure) index() map[string]*PathInfo {
+	idx := map[string]*PathInfo{}
+	for i := range c.Paths {
+		idx[c.Paths[i].Path] = &c.Paths[i]
+	}
+
+	return idx
+}
+
+// WhyDepends returns the part of the closure through which the root depends on the dependency
+// and one of the shortest chains of references between both, like "nix why-depends" does.
+func (c *Closure) WhyDepends(dependency string) (*Closure, error) {
+	idx := c.index()
+
+	if _, ok := idx[dependency]; !ok {
+		return nil, fmt.Errorf("'%s' does not depend on '%s'", c.Root, dependency)
+	}
+
+	referrers := map[string][]string{}
+	for _, info := range c.Paths {
+		for _, ref := range info.References {
+			if ref != info.Path {
+				referrers[ref] = append(referrers[ref], info.Path)
+			}
+		}
+	}
+
+	// All paths from which the dependency can be reached
+	reaches := map[string]bool{dependency: true}
+	queue := []string{dependency}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+
+		for _, referrer := range referrers[path] {
+			if !reaches[referrer] {
+				reaches[referrer] = true
+				queue = append(queue, referrer)
+			}
+		}
+	}
+
+	if !reaches[c.Root] {
+		return nil, fmt.Errorf("'%s' does not depend on '%s'", c.Root, dependency)
+	}
+
+	// Shortest chain from the root to the dependency
+	parents := map[string]string{c.Root: ""}
+	queue = []string{c.Root}
+	for len(queue) > 0 && queue[0] != dependency {
+		path := queue[0]
+		queue = queue[1:]
+
+		for _, ref := range idx[path].References {
+			if _, visited := parents[ref]; !visited && reaches[ref] {
+				parents[ref] = path
+				queue = append(queue, ref)
+			}
+		}
+	}
+
+	chain := []string{}
+	for path := dependency; path != ""; path = parents[path] {
+		chain = append(chain, path)
+	}
+	slices.Reverse(chain)
+
+	infos := []PathInfo{}
+	for _, info := range c.Paths {
+		if !reaches[info.Path] {
+			continue
+		}
+
+		info.References = slices.DeleteFunc(slices.Clone(info.References), func(ref string) bool {
+			return !reaches[ref]
+		})
+
+		infos = append(infos, info)
+	}
+
+	sc := NewClosure(c.Root, infos)
+	sc.Dependency = dependency
+	sc.Chain = chain
+
+	return sc, nil
+}
+
+// WriteDOT renders the reference graph of the closure in the GraphViz DOT language.
+func (c *Closure) WriteDOT(wr io.Writer) error {
+	var sb strings.Builder
+
+	sb.WriteString("digraph closure {\n")
+	sb.WriteString("\trankdir=LR;\n")
+	sb.WriteString("\tnode [shape=box, style=rounded, fontname=monospace];\n")
+
+	chain := map[string]bool{}
+	for i := 0; i+1 < len(c.Chain); i++ {
+		chain[c.Chain[i]+" "+c.Chain[i+1]] = true
+	}
+
+	for _, info := range c.Paths {
+		attrs := fmt.Sprintf("label=%q, tooltip=%q", fmt.Sprintf("%s\n%s", info.Name(), FormatSize(info.NarSize)), info.Path)
+		if info.Path == c.Root || info.Path == c.Dependency {
+			attrs += ", penwidth=2"
+		}
+
+		fmt.Fprintf(&sb, "\t%q [%s];\n", info.Path, attrs)
+	}
+
+	for _, info := range c.Paths {
+		for _, ref := range info.References {
+			if ref == info.Path {
+				continue
+			}
+
+			if chain[info.Path+" "+ref] {
+				fmt.Fprintf(&sb, "\t%q -> %q [penwidth=2];\n", info.Path, ref)
+			} else {
+				fmt.Fprintf(&sb, "\t%q -> %q;\n", info.Path, ref)
+			}
+		}
+	}
+
+	sb.WriteString("}\n")
+
+	_, err := io.WriteString(wr, sb.String())
+
+	return err
+}
+
+// FormatSize formats a size in bytes with binary prefixes.
+func FormatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,99 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+)
+
+const (
+	testApp    = "/nix/store/00000000000000000000000000000000-app-1.0"
+	testLib    = "/nix/store/11111111111111111111111111111111-lib-2.0"
+	testLibc   = "/nix/store/22222222222222222222222222222222-libc-2.40"
+	testAssets = "/nix/store/33333333333333333333333333333333-assets"
+)
+
+func testClosure() *nix.Closure {
+	return nix.NewClosure(testApp, []nix.PathInfo{
+		{Path: testApp, NarSize: 1000, References: []string{testApp, testLib, testAssets}},
+		{Path: testLib, NarSize: 2000, References: []string{testLibc}},
+		{Path: testLibc, NarSize: 3 << 20, References: []string{testLibc}},
+		{Path: testAssets, NarSize: 500},
+	})
+}
+
+func TestClosureWhyDepends(t *testing.T) {
+	c := testClosure()
+
+	if c.ClosureSize != 3500+3<<20 {
+		t.Errorf("ClosureSize = %d", c.ClosureSize)
+	}
+
+	wd, err := c.WhyDepends(testLibc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{testApp, testLib, testLibc}; !reflect.DeepEqual(wd.Chain, want) {
+		t.Errorf("Chain = %v, want %v", wd.Chain, want)
+	}
+
+	paths := []string{}
+	for _, info := range wd.Paths {
+		paths = append(paths, info.Path)
+	}
+
+	if want := []string{testApp, testLib, testLibc}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Paths = %v, want %v", paths, want)
+	}
+
+	if refs := wd.Paths[0].References; !reflect.DeepEqual(refs, []string{testApp, testLib}) {
+		t.Errorf("References = %v", refs)
+	}
+
+	// The original closure must not be modified
+	if refs := c.Paths[0].References; len(refs) != 3 {
+		t.Errorf("References = %v", refs)
+	}
+
+	if _, err := c.WhyDepends("/nix/store/44444444444444444444444444444444-other"); err == nil {
+		t.Error("expected error for path outside of the closure")
+	}
+
+	if _, err := nix.NewClosure(testLib, c.Paths).WhyDepends(testAssets); err == nil {
+		t.Error("expected error for unrelated path")
+	}
+}
+
+func TestClosureWriteDOT(t *testing.T) {
+	wd, err := testClosure().WhyDepends(testLibc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := wd.WriteDOT(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	dot := sb.String()
+
+	for _, expected := range []string{
+		"digraph closure {",
+		`"` + testLibc + `" [label="libc-2.40\n3.0 MiB", tooltip="` + testLibc + `", penwidth=2];`,
+		`"` + testApp + `" -> "` + testLib + `" [penwidth=2];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			t.Errorf("DOT does not contain %q:\n%s", expected, dot)
+		}
+	}
+
+	if strings.Contains(dot, testAssets) || strings.Contains(dot, `"`+testApp+`" -> "`+testApp+`"`) {
+		t.Errorf("DOT contains unexpected nodes or edges:\n%s", dot)
+	}
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeUpdated = "updated"
+)
+
+// DiffSizeThreshold is the minimum size change of a package with unchanged versions which is reported.
+// It matches the threshold of "nix store diff-closures".
+const DiffSizeThreshold = 8 << 10
+
+var outputSuffixRegex = regexp.MustCompile(`^(.*)-(bin|dev|doc|debug|devdoc|info|lib|lib32|lib64|man|out|static)$`)
+
+// ClosureDiff describes the differences between two closures like "nix store diff-closures" does.
+type ClosureDiff struct {
+	Old       string          `json:"old"`
+	New       string          `json:"new"`
+	OldSize   int64           `json:"oldSize"`
+	NewSize   int64           `json:"newSize"`
+	SizeDelta int64           `json:"sizeDelta"`
+	Changes   []PackageChange `json:"changes"`
+}
+
+type PackageChange struct {
+	Name        string   `json:"name"`
+	Change      string   `json:"change"`
+	OldVersions []string `json:"oldVersions,omitempty"`
+	NewVersions []string `json:"newVersions,omitempty"`
+	SizeDelta   int64    `json:"sizeDelta"`
+}
+
+// ParseName splits the name of a store path into package name and version.
+// Suffixes of common derivation outputs are dropped.
+// The version starts at the first dash which is followed by a digit, like in Nix's "parseDrvName".
+func ParseName(name string) (pname, version string) {
+	if m := outputSuffixRegex.FindStringSubmatch(name); m != nil {
+		name = m[1]
+	}
+
+	for i := 0; i+1 < len(name); i++ {
+		if name[i] == '-' && name[i+1] >= '0' && name[i+1] <= '9' {
+			return name[:i], name[i+1:]
+		}
+	}
+
+	return name, ""
+}
+
+type packageInfo struct {
+	versions map[string]bool
+	size     int64
+}
+
+func (c *Closure) packages() map[string]*packageInfo {
+	pkgs := map[string]*packageInfo{}
+
+	for _, info := range c.Paths {
+		pname, version := ParseName(info.Name())
+
+		pkg, ok := pkgs[pname]
+		if !ok {
+			pkg = &packageInfo{versions: map[string]bool{}}
+			pkgs[pname] = pkg
+		}
+
+		pkg.versions[version] = true
+		pkg.size += info.NarSize
+	}
+
+	return pkgs
+}
+
+// DiffClosures compares the packages in two closures by their names, versions and sizes.
+func DiffClosures(from, to *Closure) *ClosureDiff {
+	d := &ClosureDiff{
+		Old:       from.Root,
+		New:       to.Root,
+		OldSize:   from.ClosureSize,
+		NewSize:   to.ClosureSize,
+		SizeDelta: to.ClosureSize - from.ClosureSize,
+		Changes:   []PackageChange{},
+	}
+
+	oldPkgs, newPkgs := from.packages(), to.packages()
+
+	names := slices.Collect(maps.Keys(oldPkgs))
+	for name := range newPkgs {
+		if _, ok := oldPkgs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	for _, name := range names {
+		oldPkg, newPkg := oldPkgs[name], newPkgs[name]
+
+		c := PackageChange{
+			Name: name,
+		}
+
+		switch {
+		case oldPkg == nil:
+			c.Change = ChangeAdded
+			c.NewVersions = slices.Sorted(maps.Keys(newPkg.versions))
+			c.SizeDelta = newPkg.size
+
+		case newPkg == nil:
+			c.Change = ChangeRemoved
+			c.OldVersions = slices.Sorted(maps.Keys(oldPkg.versions))
+			c.SizeDelta = -oldPkg.size
+
+		default:
+			// Versions contained in both closures are not reported
+			for version := range oldPkg.versions {
+				if !newPkg.versions[version] {
+					c.OldVersions = append(c.OldVersions, version)
+				}
+			}
+
+			for version := range newPkg.versions {
+				if !oldPkg.versions[version] {
+					c.NewVersions = append(c.NewVersions, version)
+				}
+			}
+
+			slices.Sort(c.OldVersions)
+			slices.Sort(c.NewVersions)
+
+			c.Change = ChangeUpdated
+			c.SizeDelta = newPkg.size - oldPkg.size
+
+			if len(c.OldVersions) == 0 && len(c.NewVersions) == 0 && abs(c.SizeDelta) < DiffSizeThreshold {
+				continue
+			}
+		}
+
+		d.Changes = append(d.Changes, c)
+	}
+
+	return d
+}
+
+// VersionList formats versions like "nix store diff-closures" does.
+func VersionList(versions []string) string {
+	if len(versions) == 0 {
+		return "∅"
+	}
+
+	labels := []string{}
+	for _, version := range versions {
+		if version == "" {
+			version = "ε"
+		}
+
+		labels = append(labels, version)
+	}
+
+	return strings.Join(labels, ", ")
+}
+
+func abs(i int64) int64 {
+	if i < 0 {
+		return -i
+	}
+
+	return i
+}
@@ -0,0 +1,63 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+)
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		name, pname, version string
+	}{
+		{"hello-2.12.1", "hello", "2.12.1"},
+		{"bash-interactive-5.2p37", "bash-interactive", "5.2p37"},
+		{"openssl-3.0.14-dev", "openssl", "3.0.14"},
+		{"source", "source", ""},
+		{"util-linux-minimal-2.39.4-lib", "util-linux-minimal", "2.39.4"},
+		{"python3.12-requests-2.32.3", "python3.12-requests", "2.32.3"},
+	}
+
+	for _, tt := range tests {
+		if pname, version := nix.ParseName(tt.name); pname != tt.pname || version != tt.version {
+			t.Errorf("ParseName(%s) = %s, %s, want %s, %s", tt.name, pname, version, tt.pname, tt.version)
+		}
+	}
+}
+
+func TestDiffClosures(t *testing.T) {
+	from := nix.NewClosure("/nix/store/00000000000000000000000000000000-app-1.0", []nix.PathInfo{
+		{Path: "/nix/store/00000000000000000000000000000000-app-1.0", NarSize: 1000},
+		{Path: "/nix/store/11111111111111111111111111111111-openssl-3.0.13", NarSize: 50000},
+		{Path: "/nix/store/22222222222222222222222222222222-glibc-2.39", NarSize: 100000},
+		{Path: "/nix/store/33333333333333333333333333333333-zlib-1.3", NarSize: 200},
+	})
+
+	to := nix.NewClosure("/nix/store/44444444444444444444444444444444-app-1.1", []nix.PathInfo{
+		{Path: "/nix/store/44444444444444444444444444444444-app-1.1", NarSize: 1100},
+		{Path: "/nix/store/55555555555555555555555555555555-openssl-3.0.14", NarSize: 52000},
+		{Path: "/nix/store/66666666666666666666666666666666-glibc-2.39", NarSize: 100100},
+		{Path: "/nix/store/77777777777777777777777777777777-curl-8.9.1", NarSize: 30000},
+	})
+
+	d := nix.DiffClosures(from, to)
+
+	if d.SizeDelta != 283200-251200 {
+		t.Errorf("SizeDelta = %d", d.SizeDelta)
+	}
+
+	expected := []nix.PackageChange{
+		{Name: "app", Change: nix.ChangeUpdated, OldVersions: []string{"1.0"}, NewVersions: []string{"1.1"}, SizeDelta: 100},
+		{Name: "curl", Change: nix.ChangeAdded, NewVersions: []string{"8.9.1"}, SizeDelta: 30000},
+		{Name: "openssl", Change: nix.ChangeUpdated, OldVersions: []string{"3.0.13"}, NewVersions: []string{"3.0.14"}, SizeDelta: 2000},
+		{Name: "zlib", Change: nix.ChangeRemoved, OldVersions: []string{"1.3"}, SizeDelta: -200},
+	}
+
+	if !reflect.DeepEqual(d.Changes, expected) {
+		t.Errorf("Changes = %+v, want %+v", d.Changes, expected)
+	}
+}
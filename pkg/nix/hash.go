@@ -0,0 +1,121 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const nix32Alphabet = "0123456789abcdfghijklmnpqrsvwxyz"
+
+type Hash struct {
+	Type   string
+	Digest []byte
+}
+
+// ParseHash parses a hash in SRI ("sha256-<base64>") or Nix ("sha256:<nix32|hex>") format.
+func ParseHash(s string) (h Hash, err error) {
+	if typ, digest, ok := strings.Cut(s, "-"); ok && !strings.Contains(typ, ":") {
+		h.Type = typ
+		if h.Digest, err = base64.StdEncoding.DecodeString(digest); err != nil {
+			return h, fmt.Errorf("invalid SRI hash: %w", err)
+		}
+
+		return h, nil
+	}
+
+	typ, digest, ok := strings.Cut(s, ":")
+	if !ok {
+		return h, fmt.Errorf("invalid hash: %s", s)
+	}
+
+	h.Type = typ
+
+	switch len(digest) {
+	case hex.EncodedLen(hashSize(typ)):
+		h.Digest, err = hex.DecodeString(digest)
+	case nix32EncodedLen(hashSize(typ)):
+		h.Digest, err = decodeNix32(digest, hashSize(typ))
+	default:
+		err = fmt.Errorf("invalid hash length: %s", s)
+	}
+
+	return h, err
+}
+
+// String returns the hash in the "sha256:<nix32>" format used by narinfo files.
+func (h Hash) String() string {
+	return h.Type + ":" + EncodeNix32(h.Digest)
+}
+
+func (h Hash) SRI() string {
+	return h.Type + "-" + base64.StdEncoding.EncodeToString(h.Digest)
+}
+
+func hashSize(typ string) int {
+	switch typ {
+	case "md5":
+		return 16
+	case "sha1":
+		return 20
+	case "sha256":
+		return 32
+	case "sha512":
+		return 64
+	default:
+		return 0
+	}
+}
+
+func nix32EncodedLen(n int) int {
+	if n == 0 {
+		return 0
+	}
+
+	return (n*8-1)/5 + 1
+}
+
+// EncodeNix32 encodes bytes in Nix's base32 variant.
+func EncodeNix32(b []byte) string {
+	n := nix32EncodedLen(len(b))
+	s := make([]byte, 0, n)
+
+	for k := n - 1; k >= 0; k-- {
+		i, j := k*5/8, uint(k*5%8)
+
+		c := b[i] >> j
+		if i+1 < len(b) {
+			c |= b[i+1] << (8 - j)
+		}
+
+		s = append(s, nix32Alphabet[c&0x1f])
+	}
+
+	return string(s)
+}
+
+func decodeNix32(s string, size int) ([]byte, error) {
+	b := make([]byte, size)
+
+	for k := 0; k < len(s); k++ {
+		digit := strings.IndexByte(nix32Alphabet, s[len(s)-k-1])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid nix32 character: %c", s[len(s)-k-1])
+		}
+
+		i, j := k*5/8, uint(k*5%8)
+
+		b[i] |= byte(digit << j)
+		if i+1 < size {
+			b[i+1] |= byte(digit >> (8 - j))
+		} else if digit>>(8-j) != 0 {
+			return nil, fmt.Errorf("invalid nix32 hash: %s", s)
+		}
+	}
+
+	return b, nil
+}
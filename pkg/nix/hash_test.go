@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix_test
+
+import (
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+)
+
+func TestParseHash(t *testing.T) {
+	tests := []struct {
+		Name  string
+		Input string
+	}{
+		{
+			Name:  "sri",
+			Input: "sha256-LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=",
+		},
+		{
+			Name:  "nix32",
+			Input: "sha256:094qif9n4cq4fdg459qzbhg1c6wywawwaaivx0k0x8xhbyx4vwic",
+		},
+		{
+			Name:  "hex",
+			Input: "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			h, err := nix.ParseHash(tt.Input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s := h.String(); s != "sha256:094qif9n4cq4fdg459qzbhg1c6wywawwaaivx0k0x8xhbyx4vwic" {
+				t.Errorf("String() = %s", s)
+			}
+
+			if s := h.SRI(); s != "sha256-LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=" {
+				t.Errorf("SRI() = %s", s)
+			}
+		})
+	}
+}
+
+func TestParseHashInvalid(t *testing.T) {
+	for _, input := range []string{
+		"sha256",
+		"sha256:abc",
+		"sha256:e94qif9n4cq4fdg459qzbhg1c6wywawwaaivx0k0x8xhbyx4vwi!",
+	} {
+		if _, err := nix.ParseHash(input); err == nil {
+			t.Errorf("Expected error for %s", input)
+		}
+	}
+}
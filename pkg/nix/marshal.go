@@ -7,7 +7,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"reflect"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -50,7 +52,7 @@ func marshalValue(v reflect.Value, wr io.Writer, indent string, level int) (err
 		return nil
 	}
 
-	if v.Kind() == reflect.Pointer && v.IsNil() {
+	if !v.IsValid() || (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && v.IsNil() {
 		return writeString("null")
 	}
 
@@ -90,10 +92,11 @@ func marshalValue(v reflect.Value, wr io.Writer, indent string, level int) (err
 			return err
 		}
 
-	case float32, float64:
-		if _, err := fmt.Fprintf(wr, "%f", w); err != nil {
-			return err
-		}
+	case float32:
+		return writeFloat(wr, float64(w), 32)
+
+	case float64:
+		return writeFloat(wr, w, 64)
 
 	case bool:
 		if _, err := fmt.Fprint(wr, strconv.FormatBool(w)); err != nil {
@@ -102,7 +105,7 @@ func marshalValue(v reflect.Value, wr io.Writer, indent string, level int) (err
 
 	default:
 		switch v.Kind() {
-		case reflect.Ptr:
+		case reflect.Ptr, reflect.Interface:
 			return marshalValue(v.Elem(), wr, indent, level)
 
 		case reflect.Slice, reflect.Array:
@@ -166,7 +169,7 @@ func marshalValue(v reflect.Value, wr io.Writer, indent string, level int) (err
 					}
 				}
 
-				if err := writeString(kv.Key); err != nil {
+				if err := writeString(attrName(kv.Key)); err != nil {
 					return err
 				}
 				if err := writeString(" = "); err != nil {
@@ -198,6 +201,41 @@ func marshalValue(v reflect.Value, wr io.Writer, indent string, level int) (err
 	return nil
 }
 
+// writeFloat writes the shortest representation of a float which is a valid Nix float literal.
+// Nix requires a decimal point in float literals, also in those with an exponent.
+func writeFloat(wr io.Writer, f float64, bitSize int) error {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("unsupported float value: %v", f)
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if !strings.Contains(s, ".") {
+		mantissa, exponent, _ := strings.Cut(s, "e")
+		s = mantissa + ".0"
+		if exponent != "" {
+			s += "e" + exponent
+		}
+	}
+
+	_, err := io.WriteString(wr, s)
+
+	return err
+}
+
+var (
+	identifierRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_'-]*$`)
+	keywords        = []string{"assert", "else", "if", "in", "inherit", "let", "or", "rec", "then", "with"}
+)
+
+// attrName quotes attribute names which are not valid Nix identifiers.
+func attrName(key string) string {
+	if identifierRegex.MatchString(key) && !slices.Contains(keywords, key) {
+		return key
+	}
+
+	return `"` + EscapeString(key) + `"`
+}
+
 type keyValue struct {
 	reflect.Value
 	Key string
@@ -59,7 +59,27 @@ func TestMarshal(t *testing.T) {
 		{
 			Name:     "float",
 			Input:    123.456,
-			Expected: "123.456000",
+			Expected: "123.456",
+		},
+		{
+			Name:     "float small",
+			Input:    1e-7,
+			Expected: "1.0e-07",
+		},
+		{
+			Name:     "float large",
+			Input:    1.5e300,
+			Expected: "1.5e+300",
+		},
+		{
+			Name:     "float integral",
+			Input:    2.0,
+			Expected: "2.0",
+		},
+		{
+			Name:     "float32",
+			Input:    float32(0.1),
+			Expected: "0.1",
 		},
 		{
 			Name:     "bool true",
@@ -179,6 +199,16 @@ func TestMarshal(t *testing.T) {
 			Indent:   "  ",
 			Expected: "{\n  Name = \"test\";\n  Value = {\n    Number = 42;\n    Slice = [\n      1\n      2\n      3\n    ];\n  };\n}",
 		},
+		{
+			Name:     "map with interface values",
+			Input:    map[string]any{"list": []any{1, "two", nil}, "nested": map[string]any{"ok": true}},
+			Expected: `{ list = [ 1 "two" null ]; nested = { ok = true; }; }`,
+		},
+		{
+			Name:     "map with non-identifier keys",
+			Input:    map[string]int{"Content-Type": 1, "with space": 2, "1st": 3, "in": 4},
+			Expected: `{ "1st" = 3; Content-Type = 1; "in" = 4; "with space" = 2; }`,
+		},
 		{
 			Name:     "error",
 			Input:    fmt.Errorf("test error"),
@@ -0,0 +1,155 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// WriteNAR serializes the file system object at path into the Nix Archive (NAR) format.
+// This is equivalent to "nix store dump-path", but does not require a Nix process.
+func WriteNAR(wr io.Writer, path string) error {
+	nw := &narWriter{wr: wr}
+
+	nw.str("nix-archive-1")
+	nw.dump(path)
+
+	return nw.err
+}
+
+type narWriter struct {
+	wr  io.Writer
+	err error
+}
+
+func (nw *narWriter) str(s string) {
+	nw.bytes([]byte(s))
+}
+
+func (nw *narWriter) bytes(b []byte) {
+	nw.len(uint64(len(b)))
+	nw.write(b)
+	nw.pad(uint64(len(b)))
+}
+
+func (nw *narWriter) len(n uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], n)
+	nw.write(buf[:])
+}
+
+func (nw *narWriter) pad(n uint64) {
+	if r := n % 8; r != 0 {
+		nw.write(make([]byte, 8-r))
+	}
+}
+
+func (nw *narWriter) write(b []byte) {
+	if nw.err != nil {
+		return
+	}
+
+	_, nw.err = nw.wr.Write(b)
+}
+
+func (nw *narWriter) dump(path string) {
+	if nw.err != nil {
+		return
+	}
+
+	fi, err := os.Lstat(path)
+	if err != nil {
+		nw.err = err
+		return
+	}
+
+	nw.str("(")
+	nw.str("type")
+
+	switch {
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			nw.err = err
+			return
+		}
+
+		nw.str("symlink")
+		nw.str("target")
+		nw.str(target)
+
+	case fi.IsDir():
+		des, err := os.ReadDir(path)
+		if err != nil {
+			nw.err = err
+			return
+		}
+
+		// NAR requires entries to be sorted by their byte-wise name
+		names := []string{}
+		for _, de := range des {
+			names = append(names, de.Name())
+		}
+		slices.Sort(names)
+
+		nw.str("directory")
+
+		for _, name := range names {
+			nw.str("entry")
+			nw.str("(")
+			nw.str("name")
+			nw.str(name)
+			nw.str("node")
+			nw.dump(filepath.Join(path, name))
+			nw.str(")")
+		}
+
+	case fi.Mode().IsRegular():
+		nw.str("regular")
+
+		if fi.Mode()&0o111 != 0 {
+			nw.str("executable")
+			nw.str("")
+		}
+
+		nw.str("contents")
+		nw.file(path, fi.Size())
+
+	default:
+		nw.err = fmt.Errorf("unsupported file type: %s", path)
+		return
+	}
+
+	nw.str(")")
+}
+
+func (nw *narWriter) file(path string, size int64) {
+	f, err := os.Open(path)
+	if err != nil {
+		nw.err = err
+		return
+	}
+	defer f.Close() //nolint:errcheck
+
+	nw.len(uint64(size))
+
+	if nw.err != nil {
+		return
+	}
+
+	if n, err := io.Copy(nw.wr, io.LimitReader(f, size)); err != nil {
+		nw.err = err
+		return
+	} else if n != size {
+		nw.err = fmt.Errorf("file changed while reading: %s", path)
+		return
+	}
+
+	nw.pad(uint64(size))
+}
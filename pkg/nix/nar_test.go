@@ -0,0 +1,52 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix_test
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+)
+
+func TestWriteNAR(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "tree")
+
+	for name, content := range map[string]string{
+		"b.txt":     "b",
+		"a/c.txt":   "c",
+		"a/run.sh":  "#!/bin/sh",
+		"z/d/e.txt": "e",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Chmod(filepath.Join(root, "a", "run.sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink("../b.txt", filepath.Join(root, "a", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	h := sha256.New()
+	if err := nix.WriteNAR(h, root); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hash as computed by "nix hash path"
+	hash := nix.Hash{Type: "sha256", Digest: h.Sum(nil)}
+	if s := hash.SRI(); s != "sha256-NoqrXhAnAEGDc+vMR8oHsCLaG5Z5vXFCETu9YQaaF20=" {
+		t.Errorf("Hash = %s", s)
+	}
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type SecretKey struct {
+	Name string
+	Key  ed25519.PrivateKey
+}
+
+// ParseSecretKey parses a key as generated by "nix key generate-secret".
+func ParseSecretKey(s string) (*SecretKey, error) {
+	name, key, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid secret key: missing name")
+	}
+
+	b, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+
+	if len(b) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid secret key: wrong size")
+	}
+
+	return &SecretKey{
+		Name: name,
+		Key:  ed25519.PrivateKey(b),
+	}, nil
+}
+
+// PublicKey returns the public key in the format of the "trusted-public-keys" setting.
+func (k *SecretKey) PublicKey() string {
+	return k.Name + ":" + base64.StdEncoding.EncodeToString(k.Key.Public().(ed25519.PublicKey)) //nolint:forcetypeassert
+}
+
+func (k *SecretKey) Sign(msg string) string {
+	sig := ed25519.Sign(k.Key, []byte(msg))
+	return k.Name + ":" + base64.StdEncoding.EncodeToString(sig)
+}
+
+// Fingerprint returns the message which is signed by binary caches.
+func (pi *PathInfo) Fingerprint() (string, error) {
+	narHash, err := ParseHash(pi.NarHash)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("1;%s;%s;%d;%s", pi.Path, narHash, pi.NarSize, strings.Join(pi.References, ",")), nil
+}
+
+// NarInfo renders the ".narinfo" file of the path for a binary cache.
+func (pi *PathInfo) NarInfo(url, compression string, key *SecretKey) (string, error) {
+	narHash, err := ParseHash(pi.NarHash)
+	if err != nil {
+		return "", err
+	}
+
+	refs := []string{}
+	for _, ref := range pi.References {
+		refs = append(refs, filepath.Base(ref))
+	}
+
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "StorePath: %s\n", pi.Path)
+	fmt.Fprintf(&sb, "URL: %s\n", url)
+	fmt.Fprintf(&sb, "Compression: %s\n", compression)
+	fmt.Fprintf(&sb, "NarHash: %s\n", narHash)
+	fmt.Fprintf(&sb, "NarSize: %d\n", pi.NarSize)
+	fmt.Fprintf(&sb, "References: %s\n", strings.Join(refs, " "))
+
+	if pi.Deriver != "" {
+		fmt.Fprintf(&sb, "Deriver: %s\n", filepath.Base(pi.Deriver))
+	}
+
+	for _, sig := range pi.Signatures {
+		fmt.Fprintf(&sb, "Sig: %s\n", sig)
+	}
+
+	if key != nil {
+		fp, err := pi.Fingerprint()
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(&sb, "Sig: %s\n", key.Sign(fp))
+	}
+
+	if pi.CA != "" {
+		fmt.Fprintf(&sb, "CA: %s\n", pi.CA)
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/nix"
+)
+
+func TestNarInfo(t *testing.T) {
+	_, sk, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := nix.ParseSecretKey("test-1:" + base64.StdEncoding.EncodeToString(sk))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pi := nix.PathInfo{
+		Path:    "/nix/store/b5xvjs4v94h13qh8xsnkigklhm90mh3m-test",
+		NarHash: "sha256-LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ=",
+		NarSize: 120,
+		References: []string{
+			"/nix/store/a5xvjs4v94h13qh8xsnkigklhm90mh3m-dep",
+			"/nix/store/b5xvjs4v94h13qh8xsnkigklhm90mh3m-test",
+		},
+		Deriver: "/nix/store/c5xvjs4v94h13qh8xsnkigklhm90mh3m-test.drv",
+	}
+
+	ni, err := pi.NarInfo("nar/b5xvjs4v94h13qh8xsnkigklhm90mh3m.nar", "none", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `StorePath: /nix/store/b5xvjs4v94h13qh8xsnkigklhm90mh3m-test
+URL: nar/b5xvjs4v94h13qh8xsnkigklhm90mh3m.nar
+Compression: none
+NarHash: sha256:094qif9n4cq4fdg459qzbhg1c6wywawwaaivx0k0x8xhbyx4vwic
+NarSize: 120
+References: a5xvjs4v94h13qh8xsnkigklhm90mh3m-dep b5xvjs4v94h13qh8xsnkigklhm90mh3m-test
+Deriver: c5xvjs4v94h13qh8xsnkigklhm90mh3m-test.drv
+Sig: test-1:`
+
+	if !strings.HasPrefix(ni, expected) {
+		t.Fatalf("NarInfo() =\n%s\nwant prefix\n%s", ni, expected)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(ni, expected)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fp := "1;/nix/store/b5xvjs4v94h13qh8xsnkigklhm90mh3m-test;sha256:094qif9n4cq4fdg459qzbhg1c6wywawwaaivx0k0x8xhbyx4vwic;120;" +
+		"/nix/store/a5xvjs4v94h13qh8xsnkigklhm90mh3m-dep,/nix/store/b5xvjs4v94h13qh8xsnkigklhm90mh3m-test"
+
+	if !ed25519.Verify(sk.Public().(ed25519.PublicKey), []byte(fp), sig) {
+		t.Error("Invalid signature")
+	}
+}
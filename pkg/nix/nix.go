@@ -13,10 +13,17 @@ import (
 	"strings"
 
 	"al.essio.dev/pkg/shellescape"
+	"github.com/stv0g/nixpresso/pkg/limits"
 	"github.com/stv0g/nixpresso/pkg/util"
 )
 
-var Executable = "nix"
+var (
+	Executable = "nix"
+
+	// Limits and the delegated cgroup which are applied to all invocations of Nix.
+	Limits *limits.Limits
+	Cgroup string
+)
 
 func Nix(ctx context.Context, pty, verbose int, stdin io.Reader, stdout io.Writer, stderr io.Writer, argv ...string) ([]byte, []byte, error) {
 	argv2 := []string{"--extra-experimental-features", "nix-command"}
@@ -26,7 +33,20 @@ func Nix(ctx context.Context, pty, verbose int, stdin io.Reader, stdout io.Write
 
 	slog.Debug("Invoking: " + shellescape.QuoteCommand(cmd.Args))
 
-	return util.Run(cmd, pty, verbose, stdin, stdout, stderr)
+	if err := limits.Wrap(cmd, Limits); err != nil {
+		return nil, nil, err
+	}
+
+	cg, err := limits.NewCgroup(Cgroup, Limits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cg.Attach(cmd)
+
+	stdoutBytes, stderrBytes, err := util.Run(cmd, pty, verbose, stdin, stdout, stderr)
+
+	return stdoutBytes, stderrBytes, cg.Finish(err)
 }
 
 func NixUnmarshal(ctx context.Context, pty, verbose int, result any, stdin io.Reader, stderr io.Writer, argv ...string) (err error) {
@@ -0,0 +1,71 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package nix
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type PathInfo struct {
+	Path       string   `json:"path"`
+	NarHash    string   `json:"narHash"`
+	NarSize    int64    `json:"narSize"`
+	References []string `json:"references"`
+	Deriver    string   `json:"deriver,omitempty"`
+	Signatures []string `json:"signatures,omitempty"`
+	CA         string   `json:"ca,omitempty"`
+}
+
+// PathInfos queries the store for information about the paths and optionally their closures.
+func PathInfos(ctx context.Context, verbose int, recursive bool, paths ...string) ([]PathInfo, error) {
+	argv := []string{"path-info"}
+	if recursive {
+		argv = append(argv, "--recursive")
+	}
+	argv = append(argv, paths...)
+
+	var raw json.RawMessage
+	if err := NixUnmarshal(ctx, 0, verbose, &raw, nil, nil, argv...); err != nil {
+		return nil, err
+	}
+
+	var infos []PathInfo
+
+	// Nix >= 2.19 returns an object keyed by store path instead of a list
+	if trimmed := strings.TrimSpace(string(raw)); strings.HasPrefix(trimmed, "{") {
+		var m map[string]PathInfo
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal: %w", err)
+		}
+
+		for path, info := range m {
+			info.Path = path
+			infos = append(infos, info)
+		}
+	} else if err := json.Unmarshal(raw, &infos); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	}
+
+	slices.SortFunc(infos, func(a, b PathInfo) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return infos, nil
+}
+
+// HashPart returns the hash part of the store path.
+func (pi *PathInfo) HashPart() string {
+	return HashPart(pi.Path)
+}
+
+// HashPart returns the hash part of a store path like "/nix/store/<hash>-<name>".
+func HashPart(path string) string {
+	base := path[strings.LastIndexByte(path, '/')+1:]
+	hash, _, _ := strings.Cut(base, "-")
+	return hash
+}
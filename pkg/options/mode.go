@@ -14,12 +14,17 @@ const (
 	RunMode        = "run"
 	LogMode        = "log"
 	DerivationMode = "derivation"
+	CacheMode      = "cache"
+	ListingMode    = "listing"
+	ClosureMode    = "closure"
+	DiffMode       = "diff"
+	ProxyMode      = "proxy"
 )
 
 var (
-	AllModes     = []string{ServeMode, LogMode, DerivationMode, RunMode}
-	DefaultModes = []string{ServeMode, LogMode, DerivationMode}
-	BuildModes   = []string{ServeMode, LogMode, RunMode}
+	AllModes     = []string{ServeMode, LogMode, DerivationMode, RunMode, CacheMode, ListingMode, ClosureMode, DiffMode, ProxyMode}
+	DefaultModes = []string{ServeMode, LogMode, DerivationMode, ListingMode}
+	BuildModes   = []string{ServeMode, LogMode, RunMode, CacheMode, ListingMode, ClosureMode, DiffMode, ProxyMode}
 )
 
 type Modes []string
@@ -5,11 +5,15 @@ package options
 
 import (
 	"time"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/users"
 )
 
 type Options struct {
-	Handler  string `json:"handler"` // "Installable" which is passed to "nix eval" && "nix run"
-	BasePath string `json:"basePath"`
+	Handler     string `json:"handler"` // "Installable" which is passed to "nix eval" && "nix run"
+	BasePath    string `json:"basePath"`
+	OpenAPIPath string `json:"openapiPath"`
 
 	EvalCache    bool  `json:"evalCache"`
 	AllowStore   bool  `json:"allowStore"`
@@ -17,6 +21,15 @@ type Options struct {
 	AllowedModes Modes `json:"allowedModes"`
 	AllowedTypes Types `json:"allowedTypes"`
 
+	Sandbox             bool     `json:"sandbox"`
+	AllowedCapabilities []string `json:"allowedCapabilities"`
+
+	RunUsers   users.Range `json:"runUsers"`
+	SecretsDir string      `json:"secretsDir"`
+
+	CacheSecretKeyFile string `json:"cacheSecretKeyFile"`
+	DotPath            string `json:"dotPath"`
+
 	NixArgs []string `json:"nixArgs"`
 	RunArgs []string `json:"runArgs"`
 
@@ -25,8 +38,17 @@ type Options struct {
 	MaxBuildTime   time.Duration `json:"maxBuildTime"`
 	MaxRunTime     time.Duration `json:"maxRunTime"`
 
+	ProxyIdleTimeout time.Duration `json:"proxyIdleTimeout"`
+
 	MaxRequestBytes  int64 `json:"maxRequestBytes"`
 	MaxResponseBytes int64 `json:"maxResponseBytes"`
+	CompressMinBytes int64 `json:"compressMinBytes"`
+
+	Limits limits.Limits `json:"limits"`
+	Cgroup string        `json:"cgroup"`
+
+	SeccompProfiles string `json:"seccompProfiles"`
+	SeccompProfile  string `json:"seccompProfile"`
 
 	Verbose int `json:"verbose"`
 }
@@ -13,9 +13,10 @@ const (
 	StringType     = "string"
 	PathType       = "path"
 	DerivationType = "derivation"
+	ValueType      = "value"
 )
 
-var AllTypes = []string{StringType, PathType, DerivationType}
+var AllTypes = []string{StringType, PathType, DerivationType, ValueType}
 
 type Types []string
 
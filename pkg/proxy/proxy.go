@@ -0,0 +1,525 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+)
+
+const (
+	NetworkUnix = "unix"
+	NetworkTCP  = "tcp"
+)
+
+var (
+	// StartTimeout is the maximum duration until a started service accepts connections.
+	StartTimeout = 30 * time.Second
+
+	// StopTimeout is the duration after which a service which has been asked to terminate is killed.
+	StopTimeout = 10 * time.Second
+
+	ErrExited = errors.New("service exited")
+
+	unsafeNameRegex = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// Spec describes how a service is started.
+// Occurrences of "@socket@" and "@port@" in the arguments are replaced by the address on which the service must listen.
+// It is also passed in the environment variables "NIXPRESSO_SOCKET" or "PORT".
+//
+// With ListenStdin, the supervisor creates the listening socket itself and passes it as standard input,
+// like web servers do for FastCGI applications. This allows multiple workers to share the socket.
+//
+// The processes are confined by the Limits. Its quotas are only applied if the supervisor has a parent cgroup.
+//
+// Args, Env and Limits may differ between requests. They are taken from the request which started the instance.
+type Spec struct {
+	Name        string
+	Program     string
+	Args        []string
+	Env         []string
+	Network     string
+	ListenStdin bool
+	Workers     int
+	Limits      *limits.Limits
+}
+
+// equal compares the fields which identify an instance.
+func (s Spec) equal(o Spec) bool {
+	return s.Program == o.Program &&
+		s.Network == o.Network &&
+		s.ListenStdin == o.ListenStdin &&
+		s.Workers == o.Workers
+}
+
+// Supervisor keeps long-running services alive while they are used.
+type Supervisor struct {
+	idleTimeout time.Duration
+	cgroup      string
+
+	mu        sync.Mutex
+	dir       string
+	instances map[string]*Instance
+	retired   map[*Instance]struct{}
+	counter   int
+	done      chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func NewSupervisor(idleTimeout time.Duration, cgroup string) *Supervisor {
+	s := &Supervisor{
+		idleTimeout: idleTimeout,
+		cgroup:      cgroup,
+		instances:   map[string]*Instance{},
+		retired:     map[*Instance]struct{}{},
+		done:        make(chan struct{}),
+	}
+
+	if idleTimeout > 0 {
+		go s.reap()
+	}
+
+	return s
+}
+
+// Acquire returns a running instance of the service and starts it if required.
+// Instances whose spec has changed, for example because of a new build of the derivation, are replaced.
+// Replaced instances are stopped once all their leases have been released.
+// The returned function must be called once the instance is not used anymore.
+func (s *Supervisor) Acquire(ctx context.Context, spec Spec) (*Instance, func(), error) {
+	if spec.Network == "" {
+		spec.Network = NetworkUnix
+	} else if spec.Network != NetworkUnix && spec.Network != NetworkTCP {
+		return nil, nil, fmt.Errorf("unsupported network: %s", spec.Network)
+	}
+
+	if spec.Workers < 1 {
+		spec.Workers = 1
+	} else if spec.Workers > 1 && !spec.ListenStdin {
+		return nil, nil, fmt.Errorf("multiple workers require a socket passed via standard input")
+	}
+
+	s.mu.Lock()
+
+	inst, ok := s.instances[spec.Name]
+	if ok && (!inst.spec.equal(spec) || inst.hasExited()) {
+		slog.Info("Replacing service", slog.String("name", spec.Name))
+
+		delete(s.instances, spec.Name)
+		s.retire(inst)
+
+		inst = nil
+	}
+
+	if inst == nil {
+		var err error
+		if inst, err = s.start(spec); err != nil {
+			s.mu.Unlock()
+			return nil, nil, err
+		}
+
+		s.instances[spec.Name] = inst
+	}
+
+	inst.active++
+	inst.lastUsed = time.Now()
+
+	s.mu.Unlock()
+
+	release := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		inst.active--
+		inst.lastUsed = time.Now()
+
+		if _, ok := s.retired[inst]; ok && inst.active == 0 {
+			delete(s.retired, inst)
+			go inst.stop()
+		}
+	}
+
+	select {
+	case <-inst.ready:
+	case <-ctx.Done():
+		release()
+		return nil, nil, ctx.Err()
+	}
+
+	if inst.err != nil {
+		release()
+
+		s.mu.Lock()
+		if s.instances[spec.Name] == inst {
+			delete(s.instances, spec.Name)
+		}
+		s.mu.Unlock()
+
+		return nil, nil, inst.err
+	}
+
+	return inst, release, nil
+}
+
+// Close stops all services, including replaced ones which are still in use.
+// Subsequent calls return the result of the first one.
+func (s *Supervisor) Close() error {
+	s.closeOnce.Do(func() {
+		s.closeErr = s.close()
+	})
+
+	return s.closeErr
+}
+
+func (s *Supervisor) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	close(s.done)
+
+	insts := slices.Collect(maps.Values(s.instances))
+	insts = slices.AppendSeq(insts, maps.Keys(s.retired))
+
+	clear(s.instances)
+	clear(s.retired)
+
+	var wg sync.WaitGroup
+	for _, inst := range insts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			inst.stop()
+		}()
+	}
+
+	wg.Wait()
+
+	if s.dir != "" {
+		return os.RemoveAll(s.dir)
+	}
+
+	return nil
+}
+
+// retire stops the instance once it is not used anymore. The supervisor lock must be held.
+func (s *Supervisor) retire(inst *Instance) {
+	if inst.active == 0 {
+		go inst.stop()
+	} else {
+		s.retired[inst] = struct{}{}
+	}
+}
+
+func (s *Supervisor) reap() {
+	ticker := time.NewTicker(max(s.idleTimeout/4, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+
+		case <-ticker.C:
+			s.mu.Lock()
+			for name, inst := range s.instances {
+				if inst.active == 0 && time.Since(inst.lastUsed) > s.idleTimeout {
+					slog.Info("Stopping idle service", slog.String("name", name))
+
+					delete(s.instances, name)
+					go inst.stop()
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// start launches a new instance. The supervisor lock must be held.
+func (s *Supervisor) start(spec Spec) (*Instance, error) {
+	inst := &Instance{
+		spec:    spec,
+		ready:   make(chan struct{}),
+		exited:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	env := slices.Clone(spec.Env)
+	replacer := strings.NewReplacer()
+
+	switch spec.Network {
+	case NetworkUnix:
+		if s.dir == "" {
+			var err error
+			if s.dir, err = os.MkdirTemp("", "nixpresso-proxy-"); err != nil {
+				return nil, fmt.Errorf("failed to create socket directory: %w", err)
+			}
+		}
+
+		s.counter++
+		inst.addr = filepath.Join(s.dir, fmt.Sprintf("%s-%d.sock", unsafeNameRegex.ReplaceAllString(spec.Name, "_"), s.counter))
+
+		env = append(env, "NIXPRESSO_SOCKET="+inst.addr)
+		replacer = strings.NewReplacer("@socket@", inst.addr)
+
+	case NetworkTCP:
+		port, err := freePort()
+		if err != nil {
+			return nil, err
+		}
+
+		inst.addr = net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+
+		env = append(env, "PORT="+strconv.Itoa(port))
+		replacer = strings.NewReplacer("@port@", strconv.Itoa(port))
+	}
+
+	args := []string{}
+	for _, arg := range spec.Args {
+		args = append(args, replacer.Replace(arg))
+	}
+
+	var listener *os.File
+	if spec.ListenStdin {
+		var err error
+		if listener, err = listen(spec.Network, inst.addr); err != nil {
+			return nil, err
+		}
+		defer listener.Close() //nolint:errcheck
+	}
+
+	inst.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Scheme = "http"
+			pr.Out.URL.Host = "localhost"
+			pr.Out.Host = pr.In.Host
+			pr.SetXForwarded()
+		},
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, spec.Network, inst.addr)
+			},
+		},
+	}
+
+	slog.Info("Starting service",
+		slog.String("name", spec.Name),
+		slog.String("program", spec.Program),
+		slog.String("address", inst.addr),
+		slog.Int("workers", spec.Workers))
+
+	// All workers share a cgroup which is removed once the instance has stopped.
+	cg, err := limits.NewCgroup(s.cgroup, spec.Limits)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg         sync.WaitGroup
+		exitedOnce sync.Once
+	)
+
+	for range spec.Workers {
+		cmd := exec.Command(spec.Program, args...)
+		cmd.Env = env
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		cmd.SysProcAttr = sysProcAttr()
+
+		if err = limits.Wrap(cmd, spec.Limits); err != nil {
+			break
+		}
+
+		cg.Attach(cmd)
+
+		if listener != nil {
+			cmd.Stdin = listener
+		}
+
+		if err = cmd.Start(); err != nil {
+			err = fmt.Errorf("failed to start service: %w", err)
+			break
+		}
+
+		inst.cmds = append(inst.cmds, cmd)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := cmd.Wait()
+
+			slog.Info("Service exited",
+				slog.String("name", spec.Name),
+				slog.Int("pid", cmd.Process.Pid),
+				slog.Any("error", err))
+
+			// The instance is replaced as soon as any of its workers exited.
+			exitedOnce.Do(func() { close(inst.exited) })
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cg.Finish(nil) //nolint:errcheck
+		close(inst.stopped)
+	}()
+
+	if err != nil {
+		go inst.stop()
+		return nil, err
+	}
+
+	go inst.waitReady()
+
+	return inst, nil
+}
+
+// Instance is a running process of a service.
+type Instance struct {
+	spec  Spec
+	cmds  []*exec.Cmd
+	addr  string
+	proxy *httputil.ReverseProxy
+
+	ready   chan struct{}
+	err     error
+	exited  chan struct{}
+	stopped chan struct{}
+
+	// Guarded by the supervisor lock
+	active   int
+	lastUsed time.Time
+}
+
+// ServeHTTP forwards the request to the service, including WebSocket upgrades.
+func (i *Instance) ServeHTTP(wr http.ResponseWriter, req *http.Request) {
+	i.proxy.ServeHTTP(wr, req)
+}
+
+// Dial opens a connection to the service for protocols other than HTTP.
+func (i *Instance) Dial(ctx context.Context) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, i.spec.Network, i.addr)
+}
+
+// PID returns the process ID of the service or its first worker.
+func (i *Instance) PID() int {
+	return i.cmds[0].Process.Pid
+}
+
+func (i *Instance) hasExited() bool {
+	select {
+	case <-i.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (i *Instance) waitReady() {
+	defer close(i.ready)
+
+	deadline := time.Now().Add(StartTimeout)
+
+	for time.Now().Before(deadline) {
+		if i.hasExited() {
+			i.err = fmt.Errorf("failed to start service '%s': %w", i.spec.Name, ErrExited)
+			return
+		}
+
+		if conn, err := net.DialTimeout(i.spec.Network, i.addr, time.Second); err == nil {
+			conn.Close() //nolint:errcheck
+			return
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	i.err = fmt.Errorf("service '%s' did not listen on '%s' within %s", i.spec.Name, i.addr, StartTimeout)
+
+	go i.stop()
+}
+
+func (i *Instance) stop() {
+	i.signal(syscall.SIGTERM)
+
+	select {
+	case <-i.stopped:
+	case <-time.After(StopTimeout):
+		i.signal(syscall.SIGKILL)
+		<-i.stopped
+	}
+
+	if i.spec.Network == NetworkUnix {
+		os.Remove(i.addr) //nolint:errcheck
+	}
+
+	if t, ok := i.proxy.Transport.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+func (i *Instance) signal(sig syscall.Signal) {
+	for _, cmd := range i.cmds {
+		if err := syscall.Kill(-cmd.Process.Pid, sig); err != nil && !errors.Is(err, syscall.ESRCH) {
+			slog.Error("Failed to signal service",
+				slog.String("name", i.spec.Name),
+				slog.String("signal", sig.String()),
+				slog.Any("error", err))
+		}
+	}
+}
+
+// listen creates a listening socket which is inherited by the service.
+func listen(network, addr string) (*os.File, error) {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	defer l.Close() //nolint:errcheck
+
+	switch l := l.(type) {
+	case *net.UnixListener:
+		// The socket file is removed when the instance is stopped.
+		l.SetUnlinkOnClose(false)
+		return l.File()
+
+	case *net.TCPListener:
+		return l.File()
+
+	default:
+		return nil, fmt.Errorf("unsupported listener: %T", l)
+	}
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find free port: %w", err)
+	}
+	defer l.Close() //nolint:errcheck
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,238 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/proxy"
+)
+
+// The test binary itself acts as the proxied service.
+func TestMain(m *testing.M) {
+	if greeting := os.Getenv("TEST_SERVICE_GREETING"); greeting != "" {
+		serve(greeting)
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+func serve(greeting string) {
+	var (
+		l   net.Listener
+		err error
+	)
+
+	if os.Getenv("TEST_SERVICE_STDIN") != "" {
+		l, err = net.FileListener(os.Stdin)
+	} else if socket := os.Getenv("NIXPRESSO_SOCKET"); socket != "" {
+		l, err = net.Listen("unix", socket)
+	} else {
+		l, err = net.Listen("tcp", "127.0.0.1:"+os.Getenv("PORT"))
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	http.Serve(l, http.HandlerFunc(func(wr http.ResponseWriter, req *http.Request) { //nolint:errcheck
+		fmt.Fprintf(wr, "%s %s %d %s", greeting, req.URL.Path, os.Getpid(), req.Header.Get("X-Forwarded-Host"))
+	}))
+}
+
+func spec(t *testing.T, network, greeting string) proxy.Spec {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return proxy.Spec{
+		Name:    "test",
+		Program: exe,
+		Env:     []string{"TEST_SERVICE_GREETING=" + greeting},
+		Network: network,
+	}
+}
+
+// rebuilt returns another path to the program, like a new build of a derivation.
+func rebuilt(t *testing.T, program string) string {
+	path := filepath.Join(t.TempDir(), "service")
+	if err := os.Symlink(program, path); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func get(t *testing.T, s *proxy.Supervisor, sp proxy.Spec, path string) (string, int) {
+	inst, release, err := s.Acquire(context.Background(), sp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com"+path, nil)
+	rec := httptest.NewRecorder()
+
+	inst.ServeHTTP(rec, req)
+
+	body, _ := io.ReadAll(rec.Body)
+
+	return string(body), inst.PID()
+}
+
+func TestSupervisor(t *testing.T) {
+	for _, network := range []string{proxy.NetworkUnix, proxy.NetworkTCP} {
+		t.Run(network, func(t *testing.T) {
+			s := proxy.NewSupervisor(0, "")
+			defer s.Close() //nolint:errcheck
+
+			body, pid := get(t, s, spec(t, network, "hello"), "/a")
+			if expected := fmt.Sprintf("hello /a %d example.com", pid); body != expected {
+				t.Errorf("body = %q, want %q", body, expected)
+			}
+
+			// The instance is reused
+			if _, pid2 := get(t, s, spec(t, network, "hello"), "/b"); pid2 != pid {
+				t.Errorf("service was restarted: %d != %d", pid2, pid)
+			}
+
+			// The environment of later requests does not affect the running instance
+			if body, pid2 := get(t, s, spec(t, network, "bye"), "/b"); pid2 != pid || !strings.HasPrefix(body, "hello ") {
+				t.Errorf("service was restarted: %q", body)
+			}
+
+			// A changed program restarts the service
+			sp := spec(t, network, "bye")
+			sp.Program = rebuilt(t, sp.Program)
+
+			body, pid3 := get(t, s, sp, "/c")
+			if pid3 == pid {
+				t.Error("service was not restarted")
+			}
+
+			if expected := fmt.Sprintf("bye /c %d example.com", pid3); body != expected {
+				t.Errorf("body = %q, want %q", body, expected)
+			}
+		})
+	}
+}
+
+func TestSupervisorListenStdin(t *testing.T) {
+	for _, network := range []string{proxy.NetworkUnix, proxy.NetworkTCP} {
+		t.Run(network, func(t *testing.T) {
+			s := proxy.NewSupervisor(0, "")
+			defer s.Close() //nolint:errcheck
+
+			sp := spec(t, network, "hello")
+			sp.Env = append(sp.Env, "TEST_SERVICE_STDIN=1")
+			sp.ListenStdin = true
+			sp.Workers = 3
+
+			for range 5 {
+				if body, _ := get(t, s, sp, "/a"); !strings.HasPrefix(body, "hello /a ") {
+					t.Errorf("unexpected body: %q", body)
+				}
+			}
+		})
+	}
+
+	s := proxy.NewSupervisor(0, "")
+	defer s.Close() //nolint:errcheck
+
+	sp := spec(t, proxy.NetworkUnix, "hello")
+	sp.Workers = 2
+
+	if _, _, err := s.Acquire(context.Background(), sp); err == nil {
+		t.Error("expected error for multiple workers without shared socket")
+	}
+}
+
+func TestSupervisorIdle(t *testing.T) {
+	s := proxy.NewSupervisor(100*time.Millisecond, "")
+	defer s.Close() //nolint:errcheck
+
+	_, pid := get(t, s, spec(t, proxy.NetworkUnix, "hello"), "/")
+
+	time.Sleep(1500 * time.Millisecond)
+
+	if _, pid2 := get(t, s, spec(t, proxy.NetworkUnix, "hello"), "/"); pid2 == pid {
+		t.Error("idle service was not stopped")
+	}
+}
+
+func TestSupervisorFailedStart(t *testing.T) {
+	s := proxy.NewSupervisor(0, "")
+	defer s.Close() //nolint:errcheck
+
+	sp := spec(t, proxy.NetworkUnix, "")
+	sp.Program = "/bin/false"
+
+	if _, _, err := s.Acquire(context.Background(), sp); err == nil {
+		t.Error("expected error for service which exits immediately")
+	}
+}
+
+func TestSupervisorDrain(t *testing.T) {
+	s := proxy.NewSupervisor(0, "")
+	defer s.Close() //nolint:errcheck
+
+	sp := spec(t, proxy.NetworkUnix, "hello")
+
+	inst, release, err := s.Acquire(context.Background(), sp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replace the instance while it is still in use
+	sp2 := spec(t, proxy.NetworkUnix, "bye")
+	sp2.Program = rebuilt(t, sp2.Program)
+
+	if body, _ := get(t, s, sp2, "/"); !strings.HasPrefix(body, "bye ") {
+		t.Errorf("unexpected body of new instance: %q", body)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://example.com/old", nil)
+	rec := httptest.NewRecorder()
+
+	inst.ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); !strings.HasPrefix(body, "hello /old ") {
+		t.Errorf("replaced instance has been stopped while in use: %d %q", rec.Code, body)
+	}
+
+	release()
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(50 * time.Millisecond) {
+		if _, err := inst.Dial(context.Background()); err != nil {
+			return
+		}
+	}
+
+	t.Error("replaced instance has not been stopped after its last lease was released")
+}
+
+func TestSupervisorCloseTwice(t *testing.T) {
+	s := proxy.NewSupervisor(time.Second, "")
+
+	get(t, s, spec(t, proxy.NetworkUnix, "hello"), "/")
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
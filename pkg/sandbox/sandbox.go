@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sandbox runs programs in unprivileged Linux namespaces.
+//
+// Sandboxed programs are started via a re-execution of the current binary which sets up
+// the mounts before replacing itself with the program. Binaries using this package must
+// therefore call Init first thing in their main function.
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/users"
+)
+
+const (
+	// CapabilityNetwork shares the network namespace of the host.
+	CapabilityNetwork = "network"
+
+	initArg0 = "nixpresso-sandbox-init"
+)
+
+var ErrUnsupported = errors.New("sandboxing is not supported on this platform")
+
+// Config describes the environment of a sandboxed program.
+type Config struct {
+	// Paths which are bind-mounted read-only into the otherwise empty root file system.
+	Paths []string `json:"paths"`
+
+	// Capabilities which are retained. Either CapabilityNetwork or Linux capabilities like "CAP_NET_BIND_SERVICE".
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// Working directory of the program.
+	Dir string `json:"dir,omitempty"`
+
+	Hostname string `json:"hostname,omitempty"`
+
+	// Rlimits which are applied right before executing the program.
+	Limits *limits.Limits `json:"limits,omitempty"`
+
+	// User to which root inside the sandbox is mapped. Defaults to the current user.
+	User *users.User `json:"-"`
+}
+
+// ValidateCapabilities checks that all capabilities are known and allowed.
+func ValidateCapabilities(caps, allowed []string) error {
+	for _, c := range caps {
+		if c != CapabilityNetwork {
+			if _, ok := linuxCapabilities[strings.ToUpper(c)]; !ok {
+				return fmt.Errorf("unknown sandbox capability: %s", c)
+			}
+		}
+
+		if !slices.Contains(allowed, c) {
+			return fmt.Errorf("sandbox capability '%s' is not allowed. Please start Nixpresso with '--allow-capability %s'", c, c)
+		}
+	}
+
+	return nil
+}
+
+var linuxCapabilities = map[string]int{
+	"CAP_CHOWN":              0,
+	"CAP_DAC_OVERRIDE":       1,
+	"CAP_DAC_READ_SEARCH":    2,
+	"CAP_FOWNER":             3,
+	"CAP_FSETID":             4,
+	"CAP_KILL":               5,
+	"CAP_SETGID":             6,
+	"CAP_SETUID":             7,
+	"CAP_SETPCAP":            8,
+	"CAP_LINUX_IMMUTABLE":    9,
+	"CAP_NET_BIND_SERVICE":   10,
+	"CAP_NET_BROADCAST":      11,
+	"CAP_NET_ADMIN":          12,
+	"CAP_NET_RAW":            13,
+	"CAP_IPC_LOCK":           14,
+	"CAP_IPC_OWNER":          15,
+	"CAP_SYS_MODULE":         16,
+	"CAP_SYS_RAWIO":          17,
+	"CAP_SYS_CHROOT":         18,
+	"CAP_SYS_PTRACE":         19,
+	"CAP_SYS_PACCT":          20,
+	"CAP_SYS_ADMIN":          21,
+	"CAP_SYS_BOOT":           22,
+	"CAP_SYS_NICE":           23,
+	"CAP_SYS_RESOURCE":       24,
+	"CAP_SYS_TIME":           25,
+	"CAP_SYS_TTY_CONFIG":     26,
+	"CAP_MKNOD":              27,
+	"CAP_LEASE":              28,
+	"CAP_AUDIT_WRITE":        29,
+	"CAP_AUDIT_CONTROL":      30,
+	"CAP_SETFCAP":            31,
+	"CAP_MAC_OVERRIDE":       32,
+	"CAP_MAC_ADMIN":          33,
+	"CAP_SYSLOG":             34,
+	"CAP_WAKE_ALARM":         35,
+	"CAP_BLOCK_SUSPEND":      36,
+	"CAP_AUDIT_READ":         37,
+	"CAP_PERFMON":            38,
+	"CAP_BPF":                39,
+	"CAP_CHECKPOINT_RESTORE": 40,
+}
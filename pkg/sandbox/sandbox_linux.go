@@ -0,0 +1,332 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+var devices = []string{"null", "zero", "full", "random", "urandom", "tty"}
+
+// Command returns a command which runs the program inside new user, mount, PID, IPC, UTS and network namespaces.
+func Command(ctx context.Context, cfg *Config, program string, args ...string) (*exec.Cmd, error) {
+	c, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sandbox config: %w", err)
+	}
+
+	cloneFlags := syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS
+	if !slices.Contains(cfg.Capabilities, CapabilityNetwork) {
+		cloneFlags |= syscall.CLONE_NEWNET
+	}
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe", append([]string{string(c), program}, args...)...)
+	cmd.Args[0] = initArg0
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: uintptr(cloneFlags),
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: os.Getuid(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: os.Getgid(), Size: 1},
+		},
+		Pdeathsig: syscall.SIGKILL,
+	}
+
+	// Mapping another user requires CAP_SETUID and CAP_SETGID. The process only becomes
+	// this user once it switches to root inside the sandbox.
+	if u := cfg.User; u != nil {
+		cmd.SysProcAttr.UidMappings[0].HostID = int(u.UID)
+		cmd.SysProcAttr.GidMappings[0].HostID = int(u.GID)
+		cmd.SysProcAttr.GidMappingsEnableSetgroups = true
+		cmd.SysProcAttr.Credential = &syscall.Credential{
+			Groups: []uint32{},
+		}
+	}
+
+	return cmd, nil
+}
+
+// Init sets up the sandbox and executes the program if the process has been started by Command.
+// Otherwise, it returns immediately.
+func Init() {
+	if len(os.Args) < 3 || os.Args[0] != initArg0 {
+		return
+	}
+
+	runtime.LockOSThread()
+
+	if err := initSandbox(os.Args[1], os.Args[2], os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %s\n", err)
+		os.Exit(127)
+	}
+}
+
+func initSandbox(config, program string, argv []string) error {
+	var cfg Config
+	if err := json.Unmarshal([]byte(config), &cfg); err != nil {
+		return fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	lastCap, err := lastCapability()
+	if err != nil {
+		return err
+	}
+
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("failed to make mounts private: %w", err)
+	}
+
+	// Paths are opened before the new root shadows them, so that paths below /tmp can be bind-mounted as well.
+	sources := []*os.File{}
+	defer func() {
+		for _, f := range sources {
+			f.Close() //nolint:errcheck
+		}
+	}()
+
+	for _, path := range cfg.Paths {
+		f, err := os.OpenFile(path, unix.O_PATH|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", path, err)
+		}
+
+		sources = append(sources, f)
+	}
+
+	// The new root is a tmpfs which shadows /tmp in our private mount namespace only.
+	const root = "/tmp"
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=0755"); err != nil {
+		return fmt.Errorf("failed to mount root: %w", err)
+	}
+
+	// The private /tmp is mounted first as paths might be bind-mounted below it.
+	if err := os.Mkdir(filepath.Join(root, "tmp"), 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	if err := unix.Mount("tmpfs", filepath.Join(root, "tmp"), "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "mode=1777"); err != nil {
+		return fmt.Errorf("failed to mount /tmp: %w", err)
+	}
+
+	for i, path := range cfg.Paths {
+		src := fmt.Sprintf("/proc/self/fd/%d", sources[i].Fd())
+		if err := bindMount(src, filepath.Join(root, path), true); err != nil {
+			return err
+		}
+	}
+
+	if err := mountDevices(root); err != nil {
+		return err
+	}
+
+	// Mounting procfs can fail if parts of the hosts procfs are shadowed, e.g. in containers.
+	if err := os.Mkdir(filepath.Join(root, "proc"), 0o555); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+
+	unix.Mount("proc", filepath.Join(root, "proc"), "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, "") //nolint:errcheck
+
+	if err := pivotRoot(root); err != nil {
+		return err
+	}
+
+	if err := unix.Mount("", "/", "", unix.MS_BIND|unix.MS_REMOUNT|unix.MS_RDONLY|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+		return fmt.Errorf("failed to remount root read-only: %w", err)
+	}
+
+	hostname := cfg.Hostname
+	if hostname == "" {
+		hostname = "sandbox"
+	}
+
+	if err := unix.Sethostname([]byte(hostname)); err != nil {
+		return fmt.Errorf("failed to set hostname: %w", err)
+	}
+
+	dir := cfg.Dir
+	if dir == "" {
+		dir = "/"
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("failed to change directory: %w", err)
+	}
+
+	if err := dropCapabilities(cfg.Capabilities, lastCap); err != nil {
+		return err
+	}
+
+	if err := cfg.Limits.Apply(); err != nil {
+		return err
+	}
+
+	return unix.Exec(program, argv, os.Environ())
+}
+
+func bindMount(src, dst string, readOnly bool) error {
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() {
+		if err := os.MkdirAll(dst, 0o755); err != nil {
+			return err
+		}
+	} else {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return err
+		}
+
+		if f, err := os.OpenFile(dst, os.O_CREATE|os.O_RDONLY, 0o644); err != nil {
+			return err
+		} else if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	if err := unix.Mount(src, dst, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("failed to bind-mount %s: %w", dst, err)
+	}
+
+	if !readOnly {
+		return nil
+	}
+
+	// Flags locked by the parent user namespace must be retained when remounting.
+	var st unix.Statfs_t
+	if err := unix.Statfs(dst, &st); err != nil {
+		return err
+	}
+
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY | unix.MS_NOSUID | unix.MS_NODEV)
+	for stFlag, msFlag := range map[int64]uintptr{
+		unix.ST_NOEXEC:      unix.MS_NOEXEC,
+		unix.ST_NOATIME:     unix.MS_NOATIME,
+		unix.ST_NODIRATIME:  unix.MS_NODIRATIME,
+		unix.ST_RELATIME:    unix.MS_RELATIME,
+		unix.ST_SYNCHRONOUS: unix.MS_SYNCHRONOUS,
+	} {
+		if st.Flags&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+
+	if err := unix.Mount("", dst, "", flags, ""); err != nil {
+		return fmt.Errorf("failed to remount %s read-only: %w", src, err)
+	}
+
+	return nil
+}
+
+func mountDevices(root string) error {
+	for _, dev := range devices {
+		src := filepath.Join("/dev", dev)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+
+		if err := bindMount(src, filepath.Join(root, src), false); err != nil && dev != "tty" {
+			return err
+		}
+	}
+
+	for link, target := range map[string]string{
+		"fd":     "/proc/self/fd",
+		"stdin":  "/proc/self/fd/0",
+		"stdout": "/proc/self/fd/1",
+		"stderr": "/proc/self/fd/2",
+	} {
+		if err := os.Symlink(target, filepath.Join(root, "dev", link)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func pivotRoot(root string) error {
+	const oldRoot = ".oldroot"
+
+	if err := os.Mkdir(filepath.Join(root, oldRoot), 0o700); err != nil {
+		return err
+	}
+
+	if err := unix.PivotRoot(root, filepath.Join(root, oldRoot)); err != nil {
+		return fmt.Errorf("failed to pivot root: %w", err)
+	}
+
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+
+	if err := unix.Unmount("/"+oldRoot, unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("failed to unmount old root: %w", err)
+	}
+
+	return os.Remove("/" + oldRoot)
+}
+
+func lastCapability() (int, error) {
+	b, err := os.ReadFile("/proc/sys/kernel/cap_last_cap")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last capability: %w", err)
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(b)))
+}
+
+// dropCapabilities removes all but the retained capabilities from the bounding and inheritable sets.
+// So the program which is executed as root of the user namespace only gains the retained ones.
+func dropCapabilities(retain []string, lastCap int) error {
+	var keep uint64
+	for _, c := range retain {
+		if n, ok := linuxCapabilities[strings.ToUpper(c)]; ok {
+			keep |= 1 << n
+		}
+	}
+
+	for n := 0; n <= lastCap; n++ {
+		if keep&(1<<n) != 0 {
+			continue
+		}
+
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(n), 0, 0, 0); err != nil {
+			return fmt.Errorf("failed to drop capability %d: %w", n, err)
+		}
+	}
+
+	hdr := unix.CapUserHeader{Version: unix.LINUX_CAPABILITY_VERSION_3}
+	data := [2]unix.CapUserData{}
+	if err := unix.Capget(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("failed to get capabilities: %w", err)
+	}
+
+	data[0].Inheritable = uint32(keep)
+	data[1].Inheritable = uint32(keep >> 32)
+
+	if err := unix.Capset(&hdr, &data[0]); err != nil {
+		return fmt.Errorf("failed to set capabilities: %w", err)
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+
+	return nil
+}
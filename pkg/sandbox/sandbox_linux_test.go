@@ -0,0 +1,158 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package sandbox_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/sandbox"
+	"github.com/stv0g/nixpresso/pkg/users"
+)
+
+func TestMain(m *testing.M) {
+	sandbox.Init()
+
+	os.Exit(m.Run())
+}
+
+func run(t *testing.T, cfg *sandbox.Config, script string) string {
+	t.Helper()
+
+	for _, path := range []string{"/bin", "/lib", "/lib64", "/usr"} {
+		if _, err := os.Stat(path); err == nil {
+			cfg.Paths = append(cfg.Paths, path)
+		}
+	}
+
+	cmd, err := sandbox.Command(context.Background(), cfg, "/bin/sh", "-c", script)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		t.Skipf("User namespaces are not available: %s", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("failed to run: %s: %s", err, stderr.String())
+	}
+
+	return stdout.String()
+}
+
+func TestSandbox(t *testing.T) {
+	secret := t.TempDir()
+	if err := os.WriteFile(secret+"/secret", []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := run(t, &sandbox.Config{}, strings.Join([]string{
+		"echo pid=$$",
+		"echo host=$(cat /proc/sys/kernel/hostname)",
+		"touch /file 2>/dev/null && echo root=rw || echo root=ro",
+		"touch /usr/file 2>/dev/null && echo usr=rw || echo usr=ro",
+		"echo hello > /tmp/file && echo tmp=$(cat /tmp/file)",
+		"test -e /etc/passwd && echo etc=yes || echo etc=no",
+		"test -e " + secret + "/secret && echo secret=yes || echo secret=no",
+		"echo net=$(tail -n +3 /proc/net/dev | wc -l)",
+		"echo caps=$(grep CapBnd /proc/self/status | cut -f2)",
+	}, "; "))
+
+	for _, expected := range []string{
+		"pid=1",
+		"host=sandbox",
+		"root=ro",
+		"usr=ro",
+		"tmp=hello",
+		"etc=no",
+		"secret=no",
+		"net=1",
+		"caps=0000000000000000",
+	} {
+		if !strings.Contains(out, expected+"\n") {
+			t.Errorf("missing %q in output:\n%s", expected, out)
+		}
+	}
+}
+
+func TestSandboxTmpPath(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/file", []byte("visible"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := run(t, &sandbox.Config{
+		Paths: []string{dir},
+	}, "cat "+dir+"/file; echo; echo hello > /tmp/other && cat /tmp/other")
+
+	if out != "visible\nhello\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestSandboxCapabilities(t *testing.T) {
+	out := run(t, &sandbox.Config{
+		Capabilities: []string{sandbox.CapabilityNetwork, "CAP_NET_BIND_SERVICE"},
+	}, "echo net=$(tail -n +3 /proc/net/dev | wc -l); echo caps=$(grep CapBnd /proc/self/status | cut -f2)")
+
+	if strings.Contains(out, "net=1\n") {
+		t.Errorf("network namespace of host not shared:\n%s", out)
+	}
+
+	if !strings.Contains(out, "caps=0000000000000400\n") {
+		t.Errorf("unexpected capabilities:\n%s", out)
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	out := run(t, &sandbox.Config{
+		Limits: &limits.Limits{
+			OpenFiles:    42,
+			AddressSpace: 64 << 20,
+		},
+	}, "ulimit -n; ulimit -v")
+
+	if out != "42\n65536\n" {
+		t.Errorf("unexpected limits: %q", out)
+	}
+}
+
+func TestValidateCapabilities(t *testing.T) {
+	allowed := []string{sandbox.CapabilityNetwork, "CAP_NET_RAW"}
+
+	if err := sandbox.ValidateCapabilities([]string{"network", "CAP_NET_RAW"}, allowed); err != nil {
+		t.Error(err)
+	}
+
+	if err := sandbox.ValidateCapabilities([]string{"CAP_SYS_ADMIN"}, allowed); err == nil {
+		t.Error("expected error for capability which is not allowed")
+	}
+
+	if err := sandbox.ValidateCapabilities([]string{"CAP_FOO"}, []string{"CAP_FOO"}); err == nil {
+		t.Error("expected error for unknown capability")
+	}
+}
+
+func TestSandboxUser(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Mapping other users requires root")
+	}
+
+	out := run(t, &sandbox.Config{
+		User: &users.User{UID: 64000, GID: 64000},
+	}, "echo $(id -u) $(grep '^Uid:' /proc/self/status | cut -f2) $(cat /proc/self/uid_map | tr -s ' ')")
+
+	if out != "0 0 0 64000 1\n" {
+		t.Errorf("unexpected user: %q", out)
+	}
+}
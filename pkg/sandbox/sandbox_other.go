@@ -0,0 +1,19 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"os/exec"
+)
+
+// Init is a no-op on platforms without sandboxing support.
+func Init() {}
+
+// Command is not supported on this platform.
+func Command(_ context.Context, _ *Config, _ string, _ ...string) (*exec.Cmd, error) {
+	return nil, ErrUnsupported
+}
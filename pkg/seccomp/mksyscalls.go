@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build ignore
+
+// mksyscalls generates the tables of syscall names from the syscall numbers of golang.org/x/sys/unix.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	archs = map[string]string{
+		"amd64": "AUDIT_ARCH_X86_64",
+		"arm64": "AUDIT_ARCH_AARCH64",
+	}
+
+	sysnumRegex = regexp.MustCompile(`^\s*SYS_([A-Z0-9_]+)\s*=\s*(\d+)`)
+)
+
+func main() {
+	out, err := exec.Command("go", "list", "-m", "-f", "{{.Dir}}", "golang.org/x/sys").Output()
+	if err != nil {
+		log.Fatalf("Failed to find golang.org/x/sys: %s", err)
+	}
+
+	dir := strings.TrimSpace(string(out))
+	names := map[string]bool{}
+
+	for arch, auditArch := range archs {
+		f, err := os.Open(filepath.Join(dir, "unix", fmt.Sprintf("zsysnum_linux_%s.go", arch)))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>\n")
+		fmt.Fprintf(&buf, "// SPDX-License-Identifier: Apache-2.0\n\n")
+		fmt.Fprintf(&buf, "// Code generated by mksyscalls.go; DO NOT EDIT.\n\n")
+		fmt.Fprintf(&buf, "//go:build linux && %s\n\n", arch)
+		fmt.Fprintf(&buf, "package seccomp\n\n")
+		fmt.Fprintf(&buf, "import \"golang.org/x/sys/unix\"\n\n")
+		fmt.Fprintf(&buf, "const nativeArch = unix.%s\n\n", auditArch)
+		fmt.Fprintf(&buf, "var syscalls = map[string]uint32{\n")
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if m := sysnumRegex.FindStringSubmatch(scanner.Text()); m != nil {
+				name := strings.ToLower(m[1])
+				names[name] = true
+
+				fmt.Fprintf(&buf, "%q: %s,\n", name, m[2])
+			}
+		}
+
+		fmt.Fprintf(&buf, "}\n")
+
+		f.Close() //nolint:errcheck
+
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := os.WriteFile(fmt.Sprintf("syscalls_linux_%s.go", arch), src, 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// The names of all architectures are known on every platform to validate profiles.
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>\n")
+	fmt.Fprintf(&buf, "// SPDX-License-Identifier: Apache-2.0\n\n")
+	fmt.Fprintf(&buf, "// Code generated by mksyscalls.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package seccomp\n\n")
+	fmt.Fprintf(&buf, "var knownSyscalls = map[string]bool{\n")
+
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		fmt.Fprintf(&buf, "%q: true,\n", name)
+	}
+
+	fmt.Fprintf(&buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile("syscalls.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,207 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package seccomp restricts the system calls of executed programs with seccomp-bpf filters.
+//
+// Profiles are installed right before executing the program by the re-executions of the limits and sandbox packages.
+package seccomp
+
+//go:generate go run mksyscalls.go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+type Action string
+
+const (
+	ActionAllow Action = "allow"
+	ActionErrno Action = "errno"
+	ActionLog   Action = "log"
+	ActionKill  Action = "kill"
+
+	DefaultProfileName = "default"
+
+	// NoProfileName selects no profile, e.g. to disable the default one.
+	NoProfileName = "none"
+)
+
+// GlobalProfileName returns the name of the profile which is applied to all programs by default.
+func GlobalProfileName() string {
+	if Supported {
+		return DefaultProfileName
+	}
+
+	return NoProfileName
+}
+
+var (
+	AllActions = []Action{ActionAllow, ActionErrno, ActionLog, ActionKill}
+
+	ErrUnsupported = errors.New("seccomp is not supported on this platform")
+)
+
+// Rule applies an action to a set of system calls.
+type Rule struct {
+	Names  []string `json:"names"`
+	Action Action   `json:"action"`
+}
+
+// Profile is a seccomp filter with a default action and rules for specific system calls.
+// Names of system calls which do not exist on the current architecture are ignored.
+type Profile struct {
+	Name          string `json:"name,omitempty"`
+	DefaultAction Action `json:"defaultAction"`
+	Syscalls      []Rule `json:"syscalls,omitempty"`
+}
+
+// DefaultProfile kills programs which attempt to debug other processes, change mounts,
+// load kernel code or otherwise escape their confinement.
+var DefaultProfile = &Profile{
+	Name:          DefaultProfileName,
+	DefaultAction: ActionAllow,
+	Syscalls: []Rule{
+		{
+			Action: ActionKill,
+			Names: []string{
+				"acct",
+				"add_key",
+				"bpf",
+				"clock_adjtime",
+				"clock_settime",
+				"delete_module",
+				"finit_module",
+				"fsconfig",
+				"fsmount",
+				"fsopen",
+				"fspick",
+				"init_module",
+				"io_uring_enter",
+				"io_uring_register",
+				"io_uring_setup",
+				"ioperm",
+				"iopl",
+				"kcmp",
+				"kexec_file_load",
+				"kexec_load",
+				"keyctl",
+				"lookup_dcookie",
+				"mount",
+				"mount_setattr",
+				"move_mount",
+				"name_to_handle_at",
+				"open_by_handle_at",
+				"open_tree",
+				"perf_event_open",
+				"pivot_root",
+				"process_vm_readv",
+				"process_vm_writev",
+				"ptrace",
+				"quotactl",
+				"reboot",
+				"request_key",
+				"setns",
+				"settimeofday",
+				"swapoff",
+				"swapon",
+				"syslog",
+				"umount2",
+				"unshare",
+				"userfaultfd",
+				"vhangup",
+			},
+		},
+	},
+}
+
+func (p *Profile) action(syscall string) Action {
+	for _, rule := range p.Syscalls {
+		if slices.Contains(rule.Names, syscall) {
+			return rule.Action
+		}
+	}
+
+	return p.DefaultAction
+}
+
+// Validate checks the actions and the names of system calls of the profile.
+// Names must exist on at least one supported architecture to catch typos.
+// The program must be executable after installing the profile, so "execve" must be allowed.
+func (p *Profile) Validate() error {
+	if !slices.Contains(AllActions, p.DefaultAction) {
+		return fmt.Errorf("invalid default action: %q", p.DefaultAction)
+	}
+
+	for _, rule := range p.Syscalls {
+		if !slices.Contains(AllActions, rule.Action) {
+			return fmt.Errorf("invalid action: %q", rule.Action)
+		}
+
+		for _, name := range rule.Names {
+			if !knownSyscalls[name] {
+				return fmt.Errorf("unknown system call: %q", name)
+			}
+		}
+	}
+
+	if a := p.action("execve"); a != ActionAllow && a != ActionLog {
+		return fmt.Errorf("profile must allow execve")
+	}
+
+	return nil
+}
+
+// LoadProfiles reads a JSON object of named profiles from a file.
+// The built-in default profile is included unless it is overwritten.
+func LoadProfiles(path string) (map[string]*Profile, error) {
+	profiles := map[string]*Profile{
+		DefaultProfileName: DefaultProfile,
+	}
+
+	if path == "" {
+		return profiles, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seccomp profiles: %w", err)
+	}
+
+	loaded := map[string]*Profile{}
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		return nil, fmt.Errorf("failed to parse seccomp profiles: %w", err)
+	}
+
+	for name, p := range loaded {
+		if name == NoProfileName {
+			return nil, fmt.Errorf("seccomp profile name '%s' is reserved", name)
+		}
+
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid seccomp profile '%s': %w", name, err)
+		}
+
+		p.Name = name
+		profiles[name] = p
+	}
+
+	return profiles, nil
+}
+
+// ViolationError indicates that a program has been killed because of a forbidden system call.
+type ViolationError struct {
+	Err      error
+	Profiles []string
+}
+
+func (e *ViolationError) Error() string {
+	return fmt.Sprintf("%s (forbidden system call according to seccomp profiles %q)", e.Err, e.Profiles)
+}
+
+func (e *ViolationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,113 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux && (amd64 || arm64)
+
+package seccomp
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Supported indicates whether profiles can be installed on this platform.
+const Supported = true
+
+const (
+	// Offsets in struct seccomp_data
+	offsetNr   = 0
+	offsetArch = 4
+
+	// Syscalls of the x32 ABI on amd64 which must not be used to bypass the filter.
+	x32SyscallBit = 0x40000000
+)
+
+func (a Action) ret() uint32 {
+	switch a {
+	case ActionAllow:
+		return unix.SECCOMP_RET_ALLOW
+	case ActionErrno:
+		return unix.SECCOMP_RET_ERRNO | uint32(unix.EPERM)
+	case ActionLog:
+		return unix.SECCOMP_RET_LOG
+	default:
+		return unix.SECCOMP_RET_KILL_PROCESS
+	}
+}
+
+// Filter compiles the profile into a BPF program for the native architecture.
+func (p *Profile) Filter() []unix.SockFilter {
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+
+	kill := stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS)
+
+	prog := []unix.SockFilter{
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetArch),
+		jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, nativeArch, 1, 0),
+		kill,
+		stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, offsetNr),
+	}
+
+	if runtime.GOARCH == "amd64" {
+		prog = append(prog,
+			jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, 0, 1),
+			kill)
+	}
+
+	// Sorted for a deterministic filter
+	names := []string{}
+	for _, rule := range p.Syscalls {
+		names = append(names, rule.Names...)
+	}
+	sort.Strings(names)
+
+	seen := map[uint32]bool{}
+	for _, name := range names {
+		nr, ok := syscalls[name]
+		if !ok || seen[nr] {
+			continue
+		}
+
+		seen[nr] = true
+
+		action := p.action(name)
+		if action == p.DefaultAction {
+			continue
+		}
+
+		prog = append(prog,
+			jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, nr, 0, 1),
+			stmt(unix.BPF_RET|unix.BPF_K, action.ret()))
+	}
+
+	return append(prog, stmt(unix.BPF_RET|unix.BPF_K, p.DefaultAction.ret()))
+}
+
+// Install applies the profile to all threads of the current process and the programs it executes.
+func (p *Profile) Install() error {
+	filter := p.Filter()
+	prog := unix.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("failed to set no_new_privs: %w", err)
+	}
+
+	if _, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog))); errno != 0 {
+		return fmt.Errorf("failed to install seccomp profile '%s': %w", p.Name, errno)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux && (amd64 || arm64)
+
+package seccomp_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/seccomp"
+)
+
+func TestMain(m *testing.M) {
+	limits.Init()
+
+	os.Exit(m.Run())
+}
+
+func run(t *testing.T, p *seccomp.Profile, name string, args ...string) ([]byte, error) {
+	t.Helper()
+
+	cmd := exec.Command(name, args...)
+	if err := limits.Wrap(cmd, &limits.Limits{
+		Seccomp: []*seccomp.Profile{p},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmd.Output()
+}
+
+func TestInstallKill(t *testing.T) {
+	_, err := run(t, &seccomp.Profile{
+		DefaultAction: seccomp.ActionAllow,
+		Syscalls: []seccomp.Rule{
+			{Names: []string{"uname"}, Action: seccomp.ActionKill},
+		},
+	}, "/bin/sh", "-c", "exec uname")
+
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		t.Fatalf("expected exit error, got: %v", err)
+	}
+
+	if ws := ee.Sys().(syscall.WaitStatus); !ws.Signaled() || ws.Signal() != syscall.SIGSYS {
+		t.Errorf("expected termination by SIGSYS: %v", ws)
+	}
+}
+
+func TestInstallErrno(t *testing.T) {
+	out, err := run(t, &seccomp.Profile{
+		DefaultAction: seccomp.ActionAllow,
+		Syscalls: []seccomp.Rule{
+			{Names: []string{"uname"}, Action: seccomp.ActionErrno},
+		},
+	}, "/bin/sh", "-c", "uname || echo denied")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.TrimSpace(string(out)) != "denied" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestDefaultProfile(t *testing.T) {
+	if err := seccomp.DefaultProfile.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := run(t, seccomp.DefaultProfile, "/bin/sh", "-c", "echo ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(out) != "ok\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"valid", `{"strict": {"defaultAction": "errno", "syscalls": [{"names": ["execve", "read"], "action": "allow"}]}}`, ""},
+		{"action", `{"strict": {"defaultAction": "deny"}}`, "invalid default action"},
+		{"execve", `{"strict": {"defaultAction": "allow", "syscalls": [{"names": ["execve"], "action": "kill"}]}}`, "must allow execve"},
+		{"unknown", `{"strict": {"defaultAction": "allow", "syscalls": [{"names": ["ptrce"], "action": "kill"}]}}`, "unknown system call"},
+		{"reserved", `{"none": {"defaultAction": "allow"}}`, "is reserved"},
+		{"foreign", `{"strict": {"defaultAction": "allow", "syscalls": [{"names": ["open", "renameat"], "action": "kill"}]}}`, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fn := filepath.Join(t.TempDir(), "profiles.json")
+			if err := os.WriteFile(fn, []byte(tc.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			profiles, err := seccomp.LoadProfiles(fn)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected error %q, got: %v", tc.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if profiles[seccomp.DefaultProfileName] == nil || profiles["strict"] == nil {
+				t.Errorf("missing profiles: %v", profiles)
+			}
+		})
+	}
+}
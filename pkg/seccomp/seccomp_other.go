@@ -0,0 +1,14 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux || !(amd64 || arm64)
+
+package seccomp
+
+// Supported indicates whether profiles can be installed on this platform.
+const Supported = false
+
+// Install is not supported on this platform.
+func (p *Profile) Install() error {
+	return ErrUnsupported
+}
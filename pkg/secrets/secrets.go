@@ -0,0 +1,98 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package secrets provides operator-configured secrets to executed programs without passing them through Nix.
+//
+// Secrets are files in a directory like the credentials directory of systemd's LoadCredential=.
+// Their values are redacted from logs and errors.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Redacted replaces the values of secrets.
+const Redacted = "[REDACTED]"
+
+var ErrNotFound = errors.New("secret not found")
+
+// Store reads secrets from the files of a directory.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store for the directory or nil if it is empty.
+func NewStore(dir string) (*Store, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	if fi, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("invalid secrets directory: %w", err)
+	} else if !fi.IsDir() {
+		return nil, fmt.Errorf("secrets directory is not a directory: %s", dir)
+	}
+
+	return &Store{
+		dir: dir,
+	}, nil
+}
+
+// Get returns the value of a secret.
+func (s *Store) Get(name string) ([]byte, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsRune(name, '/') {
+		return nil, fmt.Errorf("invalid secret name: %q", name)
+	}
+
+	if s == nil {
+		return nil, fmt.Errorf("%w: %s. Please start Nixpresso with '--secrets-dir'", ErrNotFound, name)
+	}
+
+	value, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read secret '%s': %w", name, err)
+	}
+
+	return value, nil
+}
+
+// Redactor replaces the values of secrets in strings.
+// The zero value and nil are usable and redact nothing.
+type Redactor struct {
+	values []string
+}
+
+// Add registers a value which is redacted from now on.
+// Leading and trailing whitespace is ignored as files often end with a newline.
+func (r *Redactor) Add(value []byte) {
+	if v := strings.TrimSpace(string(value)); v != "" {
+		r.values = append(r.values, v)
+	}
+}
+
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+
+	for _, v := range r.values {
+		s = strings.ReplaceAll(s, v, Redacted)
+	}
+
+	return s
+}
+
+// RedactAll redacts a slice of strings in place.
+func (r *Redactor) RedactAll(s []string) []string {
+	for i := range s {
+		s[i] = r.Redact(s[i])
+	}
+
+	return s
+}
@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package secrets_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stv0g/nixpresso/pkg/secrets"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := secrets.NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := s.Get("token"); err != nil {
+		t.Fatal(err)
+	} else if string(value) != "s3cr3t\n" {
+		t.Errorf("unexpected value: %q", value)
+	}
+
+	if _, err := s.Get("missing"); !errors.Is(err, secrets.ErrNotFound) {
+		t.Errorf("expected not found error: %v", err)
+	}
+
+	for _, name := range []string{"", "..", "../token", "a/b"} {
+		if _, err := s.Get(name); err == nil {
+			t.Errorf("expected error for invalid name %q", name)
+		}
+	}
+
+	var none *secrets.Store
+	if _, err := none.Get("token"); !errors.Is(err, secrets.ErrNotFound) {
+		t.Errorf("expected not found error: %v", err)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	var none *secrets.Redactor
+	if none.Redact("s3cr3t") != "s3cr3t" {
+		t.Error("nil redactor must not redact")
+	}
+
+	r := &secrets.Redactor{}
+	r.Add([]byte("s3cr3t\n"))
+	r.Add([]byte("  "))
+
+	if got := r.Redact("token=s3cr3t, again s3cr3t"); got != "token=[REDACTED], again [REDACTED]" {
+		t.Errorf("unexpected redaction: %q", got)
+	}
+
+	env := r.RedactAll([]string{"TOKEN=s3cr3t", "HOME=/"})
+	if env[0] != "TOKEN=[REDACTED]" || env[1] != "HOME=/" {
+		t.Errorf("unexpected redaction: %q", env)
+	}
+}
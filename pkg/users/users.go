@@ -0,0 +1,268 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+// Package users allocates dedicated unprivileged users for executed programs.
+//
+// Each program runs under its own UID and GID from a configured range with a private home directory
+// which is removed afterwards. Starting programs as other users requires the CAP_SETUID, CAP_SETGID,
+// CAP_CHOWN, CAP_DAC_OVERRIDE and CAP_KILL capabilities.
+//
+// Processes and files which a program leaves behind are removed by a re-execution of the current binary
+// as the user before it is returned to the pool. Binaries using this package must therefore call Init
+// first thing in their main function.
+package users
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+)
+
+const (
+	maxSize     = 1 << 16
+	cleanupArg0 = "nixpresso-users-cleanup"
+)
+
+// sharedDirs are world-writable directories in which programs can leave files behind.
+var sharedDirs = []string{"/tmp", "/var/tmp", "/dev/shm"}
+
+// Range of UIDs and GIDs which are allocated to executed programs.
+// The zero value disables dedicated users.
+type Range struct {
+	First uint32 `json:"first,omitempty"`
+	Last  uint32 `json:"last,omitempty"`
+}
+
+func (r *Range) String() string {
+	if r.First == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%d-%d", r.First, r.Last)
+}
+
+// Set parses a range like "100000-100999" or a single ID.
+func (r *Range) Set(s string) error {
+	first, last, ok := strings.Cut(s, "-")
+	if !ok {
+		last = first
+	}
+
+	f, err := strconv.ParseUint(first, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid first ID: %s", first)
+	}
+
+	l, err := strconv.ParseUint(last, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid last ID: %s", last)
+	}
+
+	if f == 0 {
+		return fmt.Errorf("range must not include root")
+	} else if l < f {
+		return fmt.Errorf("last ID must not be smaller than first ID")
+	} else if l-f >= maxSize {
+		return fmt.Errorf("range must not contain more than %d IDs", maxSize)
+	}
+
+	r.First, r.Last = uint32(f), uint32(l)
+
+	return nil
+}
+
+func (r *Range) Type() string {
+	return "range"
+}
+
+func (r *Range) Size() int {
+	if r.First == 0 {
+		return 0
+	}
+
+	return int(r.Last-r.First) + 1
+}
+
+// Pool hands out the IDs of a range to one program at a time.
+// IDs are reused in a round-robin fashion to delay their reuse as long as possible.
+type Pool struct {
+	free chan uint32
+}
+
+// NewPool returns a pool for the range or nil if the range is empty.
+func NewPool(r Range) *Pool {
+	if r.Size() == 0 {
+		return nil
+	}
+
+	p := &Pool{
+		free: make(chan uint32, r.Size()),
+	}
+
+	for id := r.First; id <= r.Last && id >= r.First; id++ {
+		p.free <- id
+	}
+
+	return p
+}
+
+// Acquire allocates a user and waits until one becomes available if all are in use.
+// It returns nil if the pool is nil.
+func (p *Pool) Acquire(ctx context.Context) (*User, error) {
+	if p == nil {
+		return nil, nil
+	}
+
+	select {
+	case id := <-p.free:
+		return &User{
+			UID:  id,
+			GID:  id,
+			pool: p,
+		}, nil
+
+	case <-ctx.Done():
+		return nil, fmt.Errorf("no user available: %w", context.Cause(ctx))
+	}
+}
+
+// User is a dedicated user allocated from a pool.
+type User struct {
+	UID uint32
+	GID uint32
+
+	// Home is a private directory which is created by Apply.
+	Home string
+
+	pool *Pool
+}
+
+// Apply runs the command as the user with a private home and temporary directory.
+func (u *User) Apply(cmd *exec.Cmd) (err error) {
+	if u == nil {
+		return nil
+	}
+
+	if u.Home, err = os.MkdirTemp("", fmt.Sprintf("nixpresso-%d-*", u.UID)); err != nil {
+		return fmt.Errorf("failed to create home directory: %w", err)
+	}
+
+	if err := os.Chmod(u.Home, 0o700); err != nil {
+		return fmt.Errorf("failed to change mode of home directory: %w", err)
+	}
+
+	if err := os.Chown(u.Home, int(u.UID), int(u.GID)); err != nil {
+		return fmt.Errorf("failed to change owner of home directory: %w", err)
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:    u.UID,
+		Gid:    u.GID,
+		Groups: []uint32{},
+	}
+
+	cmd.Env = append(cmd.Environ(), "HOME="+u.Home, "TMPDIR="+u.Home)
+
+	return nil
+}
+
+// Release kills all remaining processes of the user, removes its files and returns it to its pool.
+// The user is not returned if its processes could not be killed as they would be shared with the next program.
+func (u *User) Release() error {
+	if u == nil {
+		return nil
+	}
+
+	if err := u.cleanup(); err != nil {
+		return err
+	}
+
+	defer func() {
+		u.pool.free <- u.UID
+	}()
+
+	if u.Home == "" {
+		return nil
+	}
+
+	if err := os.RemoveAll(u.Home); err != nil {
+		return fmt.Errorf("failed to remove home directory: %w", err)
+	}
+
+	return nil
+}
+
+// cleanup re-executes the current binary as the user to kill its processes and remove its files.
+// Capabilities are dropped before as they would otherwise permit signaling the processes of other users.
+func (u *User) cleanup() error {
+	cmd := &exec.Cmd{
+		Path: "/proc/self/exe",
+		Args: []string{cleanupArg0},
+		SysProcAttr: &syscall.SysProcAttr{
+			Credential: &syscall.Credential{
+				Uid:    u.UID,
+				Gid:    u.GID,
+				Groups: []uint32{},
+			},
+		},
+	}
+
+	if err := limits.Wrap(cmd, &limits.Limits{DropCapabilities: true}); err != nil {
+		return err
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to clean up user %d: %w: %s", u.UID, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// Init kills all processes of the current user and removes its files from the shared directories
+// if the process has been started by Release. Otherwise, it returns immediately.
+func Init() {
+	if len(os.Args) != 1 || os.Args[0] != cleanupArg0 {
+		return
+	}
+
+	// Signals all processes which the current one is permitted to signal, except itself.
+	if err := syscall.Kill(-1, syscall.SIGKILL); err != nil && !errors.Is(err, syscall.ESRCH) {
+		fmt.Fprintf(os.Stderr, "users: failed to kill processes: %s\n", err)
+		os.Exit(1)
+	}
+
+	uid := uint32(os.Getuid())
+	for _, dir := range sharedDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			fi, err := entry.Info()
+			if err != nil {
+				continue
+			}
+
+			if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Uid == uid {
+				if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+					fmt.Fprintf(os.Stderr, "users: %s\n", err)
+				}
+			}
+		}
+	}
+
+	os.Exit(0)
+}
@@ -0,0 +1,200 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package users_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stv0g/nixpresso/pkg/limits"
+	"github.com/stv0g/nixpresso/pkg/users"
+)
+
+func TestMain(m *testing.M) {
+	limits.Init()
+	users.Init()
+
+	os.Exit(m.Run())
+}
+
+func TestRange(t *testing.T) {
+	for _, tc := range []struct {
+		arg   string
+		first uint32
+		last  uint32
+		err   bool
+	}{
+		{"100000-100999", 100000, 100999, false},
+		{"1234", 1234, 1234, false},
+		{"0-10", 0, 0, true},
+		{"20-10", 0, 0, true},
+		{"1-100000", 0, 0, true},
+		{"a-b", 0, 0, true},
+	} {
+		var r users.Range
+		err := r.Set(tc.arg)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected error for %q", tc.arg)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tc.arg, err)
+		} else if r.First != tc.first || r.Last != tc.last {
+			t.Errorf("unexpected range for %q: %s", tc.arg, r.String())
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	if users.NewPool(users.Range{}) != nil {
+		t.Error("empty range must not create a pool")
+	}
+
+	p := users.NewPool(users.Range{First: 1000, Last: 1001})
+
+	u1, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u2, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u1.UID != 1000 || u2.UID != 1001 || u2.GID != 1001 {
+		t.Errorf("unexpected users: %d, %d", u1.UID, u2.UID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := p.Acquire(ctx); err == nil {
+		t.Error("expected error for exhausted pool")
+	}
+}
+
+func TestApply(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Starting programs as other users requires root")
+	}
+
+	p := users.NewPool(users.Range{First: 64000, Last: 64000})
+
+	u, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", `echo $(id -u) $(id -g) $(id -G) "$HOME" "$TMPDIR"; touch "$HOME/file"`)
+	if err := u.Apply(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "64000 64000 64000 " + u.Home + " " + u.Home + "\n"; string(out) != expected {
+		t.Errorf("unexpected output: %q != %q", out, expected)
+	}
+
+	if err := u.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(u.Home); !os.IsNotExist(err) {
+		t.Errorf("home directory has not been removed: %s", u.Home)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Starting programs as other users requires root")
+	}
+
+	p := users.NewPool(users.Range{First: 64000, Last: 64001})
+
+	u1, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u2, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer u2.Release() //nolint:errcheck
+
+	// The program leaves a process and a file in a shared directory behind.
+	shm := fmt.Sprintf("/dev/shm/nixpresso-test-%d", u1.UID)
+	cmd := exec.Command("/bin/sh", "-c", "touch "+shm+"; sleep 1000 >/dev/null 2>&1 & echo $!")
+	if err := u1.Apply(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !running(pid) {
+		t.Fatal("process has not been left behind")
+	}
+
+	if err := u1.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	for deadline := time.Now().Add(time.Second); running(pid); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL) //nolint:errcheck
+			t.Fatalf("process %d has not been killed", pid)
+		}
+	}
+
+	if _, err := os.Stat(shm); !os.IsNotExist(err) {
+		t.Errorf("file has not been removed: %s", shm)
+	}
+
+	if _, err := os.Stat(u1.Home); !os.IsNotExist(err) {
+		t.Errorf("home directory has not been removed: %s", u1.Home)
+	}
+
+	// Released users are reused last
+	u3, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u3.UID != u1.UID {
+		t.Errorf("unexpected user: %d", u3.UID)
+	}
+}
+
+// running checks if a process exists and has not yet terminated.
+func running(pid int) bool {
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return false
+	}
+
+	return !strings.Contains(string(status), "State:\tZ")
+}
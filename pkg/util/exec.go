@@ -11,6 +11,7 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"time"
 
 	"al.essio.dev/pkg/shellescape"
 	"github.com/creack/pty"
@@ -33,6 +34,8 @@ func (e *RunError) Error() string {
 	return fmt.Sprintf("failed to run: %s", shellescape.QuoteCommand(e.Args))
 }
 
+const ptyDrainTimeout = 100 * time.Millisecond
+
 const (
 	StdinPTY int = (1 << iota)
 	StdoutPTY
@@ -40,6 +43,12 @@ const (
 )
 
 func Run(cmd *exec.Cmd, withPTY int, verbose int, stdin io.Reader, stdout, stderr io.Writer) (stdoutBytes, stderrBytes []byte, error error) {
+	return RunInTerminal(cmd, withPTY, nil, verbose, stdin, stdout, stderr)
+}
+
+// RunInTerminal is like Run but allows to choose and change the size of the PTY.
+// A nil terminal uses the default size.
+func RunInTerminal(cmd *exec.Cmd, withPTY int, term *Terminal, verbose int, stdin io.Reader, stdout, stderr io.Writer) (stdoutBytes, stderrBytes []byte, error error) {
 	stdoutBuf := &bytes.Buffer{}
 	stderrBuf := &bytes.Buffer{}
 
@@ -70,17 +79,30 @@ func Run(cmd *exec.Cmd, withPTY int, verbose int, stdin io.Reader, stdout, stder
 		cmd.Stderr = stderr
 	}
 
+	var (
+		ptmx   *os.File
+		copied = make(chan struct{})
+	)
+
 	if withPTY != 0 {
-		f, err := pty.StartWithSize(cmd, &pty.Winsize{
-			Rows: 40,
-			Cols: 160,
-		})
+		if term == nil {
+			term = NewTerminal(0, 0)
+		}
+
+		f, err := pty.StartWithSize(cmd, term.winsize())
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed to start PTY: %w", err)
 		}
 		defer f.Close() //nolint:errcheck
 
+		term.start(f)
+		defer term.start(nil)
+
+		ptmx = f
+
 		go func() {
+			defer close(copied)
+
 			var dst io.Writer
 			if withPTY&StdoutPTY != 0 {
 				dst = stdout
@@ -88,7 +110,7 @@ func Run(cmd *exec.Cmd, withPTY int, verbose int, stdin io.Reader, stdout, stder
 				dst = stderr
 			}
 
-			if _, err := io.Copy(dst, f); err != nil && !errors.Is(err, unix.EIO) {
+			if _, err := io.Copy(dst, f); err != nil && !errors.Is(err, unix.EIO) && !errors.Is(err, os.ErrDeadlineExceeded) {
 				slog.Error("Failed to copy PTY to stdout", slog.Any("error", err))
 			}
 		}()
@@ -106,7 +128,18 @@ func Run(cmd *exec.Cmd, withPTY int, verbose int, stdin io.Reader, stdout, stder
 		}
 	}
 
-	if err := cmd.Wait(); err != nil {
+	err := cmd.Wait()
+
+	// Drain the remaining output of the PTY, but do not wait for background processes which keep it open.
+	if ptmx != nil {
+		if ptmx.SetReadDeadline(time.Now().Add(ptyDrainTimeout)) != nil {
+			ptmx.Close() //nolint:errcheck
+		}
+
+		<-copied
+	}
+
+	if err != nil {
 		return stdoutBuf.Bytes(), stderrBuf.Bytes(),
 			&RunError{
 				error:  err,
@@ -43,3 +43,9 @@ func (fw *FlushingResponseWriter) Write(p []byte) (int, error) {
 
 	return n, err
 }
+
+// Unwrap allows http.ResponseController to access the underlying response writer,
+// e.g. for hijacking connections.
+func (fw *FlushingResponseWriter) Unwrap() http.ResponseWriter {
+	return fw.ResponseWriter
+}
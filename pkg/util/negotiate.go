@@ -0,0 +1,94 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"strconv"
+	"strings"
+)
+
+type acceptSpec struct {
+	value string
+	q     float64
+}
+
+func parseAccept(header string) (specs []acceptSpec) {
+	for _, part := range strings.Split(header, ",") {
+		value, params, _ := strings.Cut(part, ";")
+
+		spec := acceptSpec{
+			value: strings.ToLower(strings.TrimSpace(value)),
+			q:     1,
+		}
+
+		if spec.value == "" {
+			continue
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			key, val, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(val), 64); err == nil {
+					spec.q = q
+				}
+			}
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs
+}
+
+// Negotiate selects the offer which is most preferred by an "Accept" header.
+// Offers are considered in order so that earlier offers win ties.
+// An empty header accepts the first offer. If no offer is acceptable, an empty string is returned.
+func Negotiate(header string, offers ...string) string {
+	if strings.TrimSpace(header) == "" {
+		if len(offers) > 0 {
+			return offers[0]
+		}
+
+		return ""
+	}
+
+	specs := parseAccept(header)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := acceptQuality(specs, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+// acceptQuality returns the quality of the most specific spec matching the offer.
+func acceptQuality(specs []acceptSpec, offer string) float64 {
+	offer = strings.ToLower(offer)
+	offerType, _, _ := strings.Cut(offer, "/")
+
+	q, specificity := 0.0, -1
+	for _, spec := range specs {
+		var s int
+
+		switch {
+		case spec.value == offer:
+			s = 2
+		case strings.HasSuffix(spec.value, "/*") && strings.TrimSuffix(spec.value, "/*") == offerType:
+			s = 1
+		case spec.value == "*/*" || spec.value == "*":
+			s = 0
+		default:
+			continue
+		}
+
+		if s > specificity {
+			q, specificity = spec.q, s
+		}
+	}
+
+	return q
+}
@@ -0,0 +1,86 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import "testing"
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"application/json", "text/html"}
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{
+			name:   "Empty header",
+			header: "",
+			want:   "application/json",
+		},
+		{
+			name:   "Browser",
+			header: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			want:   "text/html",
+		},
+		{
+			name:   "Exact match",
+			header: "application/json",
+			want:   "application/json",
+		},
+		{
+			name:   "Quality",
+			header: "application/json;q=0.5, text/html",
+			want:   "text/html",
+		},
+		{
+			name:   "Type wildcard",
+			header: "text/*",
+			want:   "text/html",
+		},
+		{
+			name:   "Wildcard prefers first offer",
+			header: "*/*",
+			want:   "application/json",
+		},
+		{
+			name:   "Excluded by quality",
+			header: "text/html;q=0, */*",
+			want:   "application/json",
+		},
+		{
+			name:   "Not acceptable",
+			header: "image/png",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Negotiate(tt.header, offers...); got != tt.want {
+				t.Errorf("Negotiate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	offers := []string{"br", "zstd", "gzip"}
+
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"gzip, deflate", "gzip"},
+		{"gzip, deflate, br, zstd", "br"},
+		{"gzip;q=1.0, zstd;q=0.5", "gzip"},
+		{"*;q=0.1, gzip", "gzip"},
+		{"identity", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Negotiate(tt.header, offers...); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/creack/pty"
+)
+
+const (
+	DefaultTerminalRows = 40
+	DefaultTerminalCols = 160
+
+	// MaxTerminalSize bounds the rows and columns of PTYs.
+	MaxTerminalSize = 1000
+)
+
+// Terminal is the size of a PTY which can be changed while the program is running.
+type Terminal struct {
+	mu   sync.Mutex
+	size pty.Winsize
+	file *os.File
+}
+
+// NewTerminal returns a terminal of the given size which is bounded to MaxTerminalSize.
+// Zero values select the default size.
+func NewTerminal(rows, cols int) *Terminal {
+	t := &Terminal{
+		size: pty.Winsize{
+			Rows: DefaultTerminalRows,
+			Cols: DefaultTerminalCols,
+		},
+	}
+
+	t.Resize(rows, cols) //nolint:errcheck
+
+	return t
+}
+
+func (t *Terminal) Size() (rows, cols int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return int(t.size.Rows), int(t.size.Cols)
+}
+
+// Resize changes the size of the PTY and signals the program with SIGWINCH.
+// Zero values keep the current size.
+func (t *Terminal) Resize(rows, cols int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if rows > 0 {
+		t.size.Rows = uint16(min(rows, MaxTerminalSize))
+	}
+
+	if cols > 0 {
+		t.size.Cols = uint16(min(cols, MaxTerminalSize))
+	}
+
+	if t.file == nil {
+		return nil
+	}
+
+	return pty.Setsize(t.file, &t.size)
+}
+
+// start attaches the PTY and applies resizes which happened while it has been started.
+func (t *Terminal) start(f *os.File) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.file = f
+
+	if f != nil {
+		pty.Setsize(f, &t.size) //nolint:errcheck
+	}
+}
+
+func (t *Terminal) winsize() *pty.Winsize {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	size := t.size
+
+	return &size
+}
+
+// resizeSequence is the xterm control sequence "CSI 8 ; rows ; cols t" for resizing the text area.
+var resizeSequence = regexp.MustCompile(`^\x1b\[8;(\d{1,5});(\d{1,5})t`)
+
+// maxResizeSequence is the length of the longest resize sequence.
+const maxResizeSequence = len("\x1b[8;65535;65535t")
+
+// ResizeReader resizes the terminal whenever the input contains a resize sequence.
+// The sequences are removed from the input which is passed on to the program.
+func (t *Terminal) ResizeReader(rd io.Reader) io.Reader {
+	return &resizeReader{
+		rd:   rd,
+		term: t,
+	}
+}
+
+type resizeReader struct {
+	rd   io.Reader
+	term *Terminal
+
+	buf     []byte
+	pending []byte // Possibly incomplete sequence
+	out     []byte
+	err     error
+}
+
+func (r *resizeReader) Read(p []byte) (int, error) {
+	if len(r.buf) < len(p) {
+		r.buf = make([]byte, len(p))
+	}
+
+	for len(r.out) == 0 && r.err == nil {
+		n, err := r.rd.Read(r.buf[:len(p)])
+		r.filter(r.buf[:n], err != nil)
+		r.err = err
+	}
+
+	if len(r.out) > 0 {
+		n := copy(p, r.out)
+		r.out = r.out[n:]
+		return n, nil
+	}
+
+	return 0, r.err
+}
+
+func (r *resizeReader) filter(b []byte, eof bool) {
+	data := append(r.pending, b...)
+	r.pending = nil
+
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\x1b')
+		if i < 0 {
+			r.out = append(r.out, data...)
+			return
+		}
+
+		r.out = append(r.out, data[:i]...)
+		data = data[i:]
+
+		if m := resizeSequence.FindSubmatch(data); m != nil {
+			rows, _ := strconv.Atoi(string(m[1]))
+			cols, _ := strconv.Atoi(string(m[2]))
+			r.term.Resize(rows, cols) //nolint:errcheck
+
+			data = data[len(m[0]):]
+		} else if !eof && len(data) < maxResizeSequence && isResizePrefix(data) {
+			r.pending = data
+			return
+		} else {
+			r.out = append(r.out, data[0])
+			data = data[1:]
+		}
+	}
+}
+
+// isResizePrefix checks if b might be completed to a resize sequence by further input.
+func isResizePrefix(b []byte) bool {
+	const prefix = "\x1b[8;"
+	if len(b) <= len(prefix) {
+		return prefix[:len(b)] == string(b)
+	}
+
+	if string(b[:len(prefix)]) != prefix {
+		return false
+	}
+
+	semicolons := 0
+	for _, c := range b[len(prefix):] {
+		switch {
+		case c == ';':
+			semicolons++
+		case c < '0' || c > '9':
+			return false
+		}
+	}
+
+	return semicolons <= 1
+}
@@ -0,0 +1,104 @@
+// SPDX-FileCopyrightText: 2025 Steffen Vogel <post@steffenvogel.de>
+// SPDX-License-Identifier: Apache-2.0
+
+package util
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNewTerminal(t *testing.T) {
+	for _, tc := range []struct {
+		rows, cols       int
+		expRows, expCols int
+	}{
+		{0, 0, DefaultTerminalRows, DefaultTerminalCols},
+		{24, 80, 24, 80},
+		{-1, 5000, DefaultTerminalRows, MaxTerminalSize},
+	} {
+		rows, cols := NewTerminal(tc.rows, tc.cols).Size()
+		if rows != tc.expRows || cols != tc.expCols {
+			t.Errorf("NewTerminal(%d, %d) = %dx%d", tc.rows, tc.cols, rows, cols)
+		}
+	}
+}
+
+func TestResizeReader(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		input            string
+		output           string
+		expRows, expCols int
+	}{
+		{"plain", "hello", "hello", 40, 160},
+		{"resize", "a\x1b[8;24;80tb", "ab", 24, 80},
+		{"multiple", "\x1b[8;24;80t\x1b[8;30;100t", "", 30, 100},
+		{"other", "\x1b[Aup\x1b[8;24x", "\x1b[Aup\x1b[8;24x", 40, 160},
+		{"incomplete", "x\x1b[8;24;", "x\x1b[8;24;", 40, 160},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, wrap := range []func(io.Reader) io.Reader{
+				func(r io.Reader) io.Reader { return r },
+				iotest.OneByteReader,
+			} {
+				term := NewTerminal(0, 0)
+
+				out, err := io.ReadAll(term.ResizeReader(wrap(strings.NewReader(tc.input))))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if string(out) != tc.output {
+					t.Errorf("unexpected output: %q", out)
+				}
+
+				if rows, cols := term.Size(); rows != tc.expRows || cols != tc.expCols {
+					t.Errorf("unexpected size: %dx%d", rows, cols)
+				}
+			}
+		})
+	}
+}
+
+type notifyWriter struct {
+	bytes.Buffer
+	written chan struct{}
+}
+
+func (w *notifyWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	select {
+	case w.written <- struct{}{}:
+	default:
+	}
+	return n, err
+}
+
+func TestRunInTerminal(t *testing.T) {
+	term := NewTerminal(24, 80)
+	stdinRd, stdinWr := io.Pipe()
+	stdout := &notifyWriter{
+		written: make(chan struct{}, 1),
+	}
+
+	// Resize after the initial size has been printed
+	go func() {
+		<-stdout.written
+		stdinWr.Write([]byte("\x1b[8;30;100t\n")) //nolint:errcheck
+	}()
+
+	cmd := exec.Command("/bin/sh", "-c", "stty size; read x; stty size")
+
+	if _, _, err := RunInTerminal(cmd, StdinPTY|StdoutPTY|StderrPTY, term, 0, term.ResizeReader(stdinRd), stdout, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := strings.ReplaceAll(stdout.String(), "\r", ""); !strings.HasPrefix(out, "24 80\n") || !strings.HasSuffix(out, "30 100\n") {
+		t.Errorf("unexpected output: %q", out)
+	}
+}